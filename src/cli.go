@@ -18,9 +18,18 @@ type App struct {
 }
 
 func Init() (a *App) {
+	a = NewApp(os.Args[1:])
+
+	kingpin.MustParse(a.cli.Parse(a.args))
+
+	return a
+}
+
+// NewApp configures commands and flags, args are not parsed yet
+func NewApp(args []string) (a *App) {
 	a = &App{
 		cli:  kingpin.New("tfconfig", "Terraform configuration manager"),
-		args: os.Args[1:],
+		args: args,
 		pwd:  pwd,
 	}
 
@@ -71,8 +80,6 @@ func Init() (a *App) {
 	ConfigureDotEnvCommand(a)
 	ConfigureBackendCommand(a)
 
-	kingpin.MustParse(a.cli.Parse(a.args))
-
 	return a
 }
 
@@ -58,6 +58,7 @@ type BackendCommand struct {
 	backendConfig         *BackendConfig
 	template              *template.Template
 	dotEnvConfig          dotEnv
+	generate              GenerateOptions
 }
 
 func ConfigureBackendCommand(a *App) {
@@ -85,6 +86,8 @@ func ConfigureBackendCommand(a *App) {
 		Default("false").
 		Short('i').
 		BoolVar(&c.invokerEnabled)
+
+	ConfigureGenerateFlags(cmd, &c.generate)
 }
 
 func (c *BackendCommand) run(context *kingpin.ParseContext) error {
@@ -97,9 +100,15 @@ func (c *BackendCommand) run(context *kingpin.ParseContext) error {
 
 	c.applyInvoker(c.backendConfig)
 
+	content := c.executeTemplate(c.template, c.backendConfig)
+
+	if !c.app.PreviewGenerated(c.backendConfigPath, content, &c.generate) {
+		return nil
+	}
+
 	c.app.AskConfirmOrSkip(c.app.isCi)
 
-	if c.app.createOrPopulateFile(c.backendConfigPath, c.executeTemplate(c.template, c.backendConfig)) {
+	if c.app.createOrPopulateFile(c.backendConfigPath, content) {
 		c.log.Info("Successfully generated: %s", filepath.Base(c.backendConfigPath))
 	} else {
 		c.log.ErrorF("I don't really know what exactly should be happen to cause that error ¯\\_(ツ)_/¯ ")
@@ -2,13 +2,15 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/ssm"
-	"github.com/joho/godotenv"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 )
 
 const (
@@ -20,7 +22,16 @@ const (
 	// The SSM API limits this to a maximum of 10 at the time of writing.
 	defaultBatchSize = 10
 
+	// defaultConcurrency is the default number of SSM batches fetched at once
+	defaultConcurrency = "4"
+
+	// defaultTimeout is the default time limit for resolving all the references
+	defaultTimeout = "2m"
+
 	defaultDotEnvFilePrefix = ".env."
+
+	// valueNotExists is used instead of values that cant be found
+	valueNotExists = "VALUE_NOT_EXISTS"
 )
 
 // templateFuncs are helper functions provided to the template.
@@ -40,15 +51,6 @@ var templateFuncs = template.FuncMap{
 	"toUpper":    strings.ToUpper,
 }
 
-type ssmVar struct {
-	envVar    string
-	parameter string
-}
-
-type ssmClient interface {
-	GetParameters(*ssm.GetParametersInput) (*ssm.GetParametersOutput, error)
-}
-
 type DotEnvCommand struct {
 	app              *App
 	log              *Log
@@ -56,13 +58,45 @@ type DotEnvCommand struct {
 	dotEnvFilePrefix string
 	dotEnvFileSource string
 	dotEnvFileOut    string
+	baseLayer        bool
+	layers           []string
+	layerFiles       []string
 	dotEnvMap        map[string]string
+	interpolated     map[string]bool
+	dotEnvLines      []dotEnvLine
 	decrypt          bool
+	strict           bool
 	exposeVars       bool
 	exportVars       bool
+	execCommand      bool
+	command          []string
+	shell            string
+	format           string
+	secretName       string
+	pathPrefixes     []string
+	pathUpperCase    bool
+	pathStripPrefix  bool
+	pathValues       map[string]string
 	template         *template.Template
+	templateOptions  templateOptions
+	aws              awsOptions
+	project          map[string]string
 	ssm              ssmClient
+	secretsManager   secretsManagerClient
+	providers        SecretProviders
+	enabledProviders []string
 	batchSize        int
+	concurrency      int
+	timeout          time.Duration
+	push             pushOptions
+	diff             diffOptions
+	lockEnabled      bool
+	locked           bool
+	frozen           bool
+	lock             lockFile
+	cache            cacheOptions
+	secretCache      *secretCache
+	resolved         []resolvedVar
 }
 
 func ConfigureDotEnvCommand(a *App) {
@@ -90,44 +124,183 @@ func ConfigureDotEnvCommand(a *App) {
 		Short('d').
 		BoolVar(&c.decrypt)
 
+	c.configureStrictFlag(cmd)
+	c.configureTemplateFlags(cmd)
+	c.configureAwsFlags(cmd)
+	c.configureLayerFlags(cmd)
+	c.configureProviderFlags(cmd)
+	c.configureResolveFlags(cmd)
+
+	cmd.Flag("lock", "Write resolved Parameter Store versions into .env.<environment>.lock, requires TFCONFIG_HASH_KEY").
+		Default("false").
+		BoolVar(&c.lockEnabled)
+
+	cmd.Flag("locked", "Resolve Parameter Store parameters by versions from .env.<environment>.lock").
+		Default("false").
+		BoolVar(&c.locked)
+
+	cmd.Flag("frozen", "Fail if current Parameter Store versions differ from .env.<environment>.lock, requires TFCONFIG_HASH_KEY").
+		Default("false").
+		BoolVar(&c.frozen)
+
+	cmd.Flag("cache", "Cache resolved Parameter Store values encrypted on disk, default: false. use --no-cache to disable it").
+		Default("false").
+		Envar("TFCONFIG_CACHE").
+		BoolVar(&c.cache.enabled)
+
+	cmd.Flag("cache-ttl", "How long cached values are used, like '15m' or '1h'").
+		Default(defaultCacheTtl).
+		DurationVar(&c.cache.ttl)
+
+	cmd.Flag("cache-key-file", "Passphrase file the cache key is derived from, OS keyring is used by default").
+		PlaceHolder("PATH").
+		Envar("TFCONFIG_CACHE_KEY_FILE").
+		StringVar(&c.cache.keyFile)
+
+	cmd.Flag("path-prefix", "Parameter Store path, every parameter under the path will be loaded as env var, can be repeated").
+		PlaceHolder("PATH").
+		StringsVar(&c.pathPrefixes)
+
+	cmd.Flag("path-upper", "Upper-case env var names of parameters loaded by path, default: true. use --no-path-upper to disable it").
+		Default("true").
+		BoolVar(&c.pathUpperCase)
+
+	cmd.Flag("path-strip-prefix", "Strip path from names of parameters loaded by path, default: true. use --no-path-strip-prefix to disable it").
+		Default("true").
+		BoolVar(&c.pathStripPrefix)
+
 	cmd.Flag("export", "Prints vars prepared for export to env via eval like 'export VAR_NAME=var_value\\n'").
 		Default("false").
 		Short('e').
 		BoolVar(&c.exportVars)
+
+	cmd.Flag("shell", "Shell dialect that vars will be quoted for, one of: "+strings.Join(shellNames(), ", ")).
+		Default(defaultShell).
+		EnumVar(&c.shell, shellNames()...)
+
+	cmd.Flag("format", "Output format for stdout or dotEnvFile, one of: "+strings.Join(dotEnvFormatNames(), ", ")).
+		Short('f').
+		EnumVar(&c.format, dotEnvFormatNames()...)
+
+	cmd.Flag("secret-name", "Name of Kubernetes Secret for 'k8s-secret' format, default: environment name").
+		StringVar(&c.secretName)
+
+	cmd.Flag("exec", "Runs the command with resolved vars merged into its environment, like 'tfconfig dotenv dev --exec -- node app.js'").
+		Default("false").
+		Short('x').
+		BoolVar(&c.execCommand)
+
+	cmd.Arg("command", "Command with arguments to run in exec mode").
+		StringsVar(&c.command)
+
+	c.configurePush(a.cli)
+	c.configureDiff(a.cli)
+	c.configureCache(a.cli)
 }
 
-func (c *DotEnvCommand) initSsmClient() {
-	awsConfig := &aws.Config{
-		LogLevel: aws.LogLevel(aws.LogOff),
-	}
+// configureStrictFlag turns strict mode on in CI by default
+func (c *DotEnvCommand) configureStrictFlag(cmd *kingpin.CmdClause) {
+	cmd.Flag("strict", "Fail without any output if some referenced value not exists, default: value of CI env var. use --no-strict to disable it").
+		Default("false").
+		Envar(CiEnvVar).
+		BoolVar(&c.strict)
+}
+
+// configureResolveFlags sets up flags of commands which resolve references
+func (c *DotEnvCommand) configureResolveFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("concurrency", "Number of Parameter Store batches fetched at once").
+		Default(defaultConcurrency).
+		IntVar(&c.concurrency)
+
+	cmd.Flag("timeout", "Time limit for resolving all the references, like '30s' or '2m', 0 disables it").
+		Default(defaultTimeout).
+		DurationVar(&c.timeout)
+}
 
-	if c.log.awsDebug {
-		awsConfig.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+func (c *DotEnvCommand) initAwsClients() {
+	awsSession := c.awsSession()
+
+	// calls are retried by withRetry
+	noRetries := aws.NewConfig().WithMaxRetries(0)
+	c.ssm = ssm.New(awsSession, noRetries)
+	c.secretsManager = secretsmanager.New(awsSession, noRetries)
+
+	// frozen mode must see current versions, cached ones can be outdated
+	if c.cache.enabled && !c.frozen {
+		c.secretCache = c.openCache(awsSession)
 	}
-	awsSession, err := session.NewSession(awsConfig)
-	c.log.must(err)
-	c.ssm = ssm.New(awsSession)
 }
 
 func (c *DotEnvCommand) run(context *kingpin.ParseContext) error {
 
-	c.template = c.parseTemplate(defaultTemplate)
+	c.template = c.referenceTemplate()
+
+	c.readDotEnvSource()
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if c.locked || c.frozen {
+		c.lock = c.readLock()
+	}
+
+	if c.refersToAws(c.dotEnvMap) {
+		c.initAwsClients()
+	}
+	c.registerProviders()
+	c.loadParameterPaths(ctx)
+	c.log.must(c.processDotEnv(ctx))
 
-	c.dotEnvMap = c.readDotEnv(GetFullPath(c.app.projectPath, c.dotEnvFileSource))
+	if c.frozen {
+		c.log.must(c.checkFrozen(c.resolved))
+	}
 
-	c.initSsmClient()
-	c.processDotEnv()
+	c.log.must(c.writeValueFiles())
+
+	if c.lockEnabled {
+		c.writeLock(c.resolved)
+	}
 
 	c.handleDotEnv()
 
 	return nil
 }
 
+// context limits resolving of references by the timeout
+func (c *DotEnvCommand) context() (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(context.Background(), c.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 func (c *DotEnvCommand) validate(context *kingpin.ParseContext) error {
+	if c.execCommand {
+		c.validateExec()
+	} else if len(c.command) > 0 {
+		c.log.ErrorFWithUsage("Unexpected arguments: %s, command can be passed only in exec mode", strings.Join(c.command, " "))
+	}
+
+	c.dotEnvFileOut = c.githubEnvFile()
 	if c.dotEnvFileOut == "" {
 		c.log.Quite()
 	}
 
+	if c.format != "" && (c.exportVars || c.execCommand) {
+		c.log.ErrorFWithUsage("Flag --format cant be used together with --export or --exec")
+	}
+	if _, err := hashKey(); err != nil && (c.lockEnabled || c.frozen) {
+		c.log.ErrorFWithUsage("Flags --lock and --frozen require value hashes: %s", err)
+	}
+	if c.secretName == "" {
+		c.secretName = strings.ToLower(c.environment)
+	}
+	if c.format == "k8s-secret" {
+		if err := validateKubernetesName(c.secretName); err != nil {
+			c.log.ErrorFWithUsage("%v", err)
+		}
+	}
+
 	c.app.ValidatePath()
 
 	c.log.ShowOpts("Environment", c.environment)
@@ -144,8 +317,10 @@ func (c *DotEnvCommand) validate(context *kingpin.ParseContext) error {
 
 	if c.dotEnvFileOut != "" {
 		c.log.ShowOpts("Destination dotEnv file", c.dotEnvFileOut)
-		if isExists, isWritable := ValidateFile(GetFullPath(c.app.projectPath, c.dotEnvFileOut)); isExists && !isWritable {
+		if isExists, isWritable := ValidateFile(c.resolvePath(c.dotEnvFileOut)); isExists && !isWritable {
 			c.log.ErrorF("dotEnv file: '%s' exists, but does'nt have write permissions", c.dotEnvFileOut)
+		} else if isExists && appendedFormats[c.format] {
+			c.log.Info("dotEnv file '%s' exists, vars will be appended", c.dotEnvFileOut)
 		} else if isExists && isWritable {
 			c.log.Warning("dotEnv file '%s' exists and will be overridden", c.dotEnvFileOut)
 		}
@@ -156,10 +331,22 @@ func (c *DotEnvCommand) validate(context *kingpin.ParseContext) error {
 		c.exposeVars = false
 	}
 
+	c.layerFiles = c.sourceLayers(c.dotEnvFileOut)
+
 	return nil
 }
 
 func (c *DotEnvCommand) handleDotEnv() {
+	if c.execCommand {
+		c.exec()
+		return
+	}
+
+	if c.format != "" {
+		c.handleFormatted()
+		return
+	}
+
 	switch c.exposeVars {
 	case true:
 		switch c.exportVars {
@@ -174,111 +361,56 @@ func (c *DotEnvCommand) handleDotEnv() {
 	}
 }
 
-func (c *DotEnvCommand) printEnvVars() {
-	for k, v := range c.dotEnvMap {
-		c.log.Printf("%s=%s ", k, v)
-	}
-}
-
-func (c *DotEnvCommand) printExportEnvVars() {
-	for k, v := range c.dotEnvMap {
-		c.log.Printf("export %s=\"%s\"\n", k, v)
-	}
-}
-
-func (c *DotEnvCommand) processDotEnv() error {
-	var ssmVars []ssmVar
-
-	uniqNames := make(map[string]bool)
-	for k, v := range c.dotEnvMap {
-		parameter, err := c.parameter(k, v)
-		c.log.must(err)
-
-		if parameter != nil {
-			uniqNames[*parameter] = true
-			ssmVars = append(ssmVars, ssmVar{k, *parameter})
-		}
-	}
-
-	c.log.Debug("uniqNames = %v", len(uniqNames))
-
-	if len(uniqNames) == 0 {
-		// Nothing to do, no SSM parameters.
-		return nil
-	}
+func (c *DotEnvCommand) handleFormatted() {
+	content, err := dotEnvFormatters[c.format](c, c.dotEnvMap)
+	c.log.must(err)
 
-	names := make([]string, len(uniqNames))
-	i := 0
-	for k := range uniqNames {
-		names[i] = k
-		i++
+	if c.exposeVars {
+		c.log.Printf("%s", content)
+		return
 	}
 
-	c.log.Debug("names = %v", len(names))
-
-	for i := 0; i < len(names); i += c.batchSize {
-		j := i + c.batchSize
-		if j > len(names) {
-			j = len(names)
-		}
-
-		c.log.Debug("Batch [%v-%v], names: %v, ssmVars: %v", i, j, len(names[i:j]), len(ssmVars[i:j]))
-
-		values, err := c.getParameters(names[i:j], c.decrypt, ssmVars)
-		c.log.Debug("Batch [%v-%v], values: %v", i, j, len(values))
-		if err != nil {
-			return err
-		}
-
-		for _, v := range ssmVars {
-			val, ok := values[v.parameter]
-			if ok {
-				c.dotEnvMap[v.envVar] = val
-			}
-		}
+	c.app.AskConfirmOrSkip(c.app.isCi)
+	if appendedFormats[c.format] {
+		c.app.AppendFile(c.resolvePath(c.dotEnvFileOut), content)
+	} else {
+		c.app.createOrPopulateFile(c.resolvePath(c.dotEnvFileOut), content)
 	}
-
-	return nil
+	c.log.Info("Successful.")
 }
 
-func (c *DotEnvCommand) getParameters(names []string, decrypt bool, ssmVars []ssmVar) (map[string]string, error) {
-	values := make(map[string]string)
-
-	input := &ssm.GetParametersInput{
-		WithDecryption: aws.Bool(decrypt),
+// resolvePath resolves relative paths from the project path, absolute ones are taken as is
+func (c *DotEnvCommand) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
 	}
+	return GetFullPath(c.app.projectPath, path)
+}
 
-	for _, n := range names {
-		input.Names = append(input.Names, aws.String(n))
+func (c *DotEnvCommand) printEnvVars() {
+	dialect := c.shellDialect()
+	for _, k := range sortedKeys(c.dotEnvMap) {
+		c.log.Printf("%s", dialect.plain(k, c.dotEnvMap[k]))
 	}
+}
 
-	c.log.Debug("REQ: Batch [%v], input.Name: %v", len(ssmVars), len(input.Names))
-
-	resp, err := c.ssm.GetParameters(input)
-	c.log.Debug("RESP: Batch [%v], resp.Parameters: %v", len(ssmVars), len(resp.Parameters))
-
-	c.log.must(err)
-
-	for _, v := range ssmVars {
-		for _, p := range resp.Parameters {
-			if strings.EqualFold(v.parameter, *p.Name) {
-				values[v.parameter] = *p.Value
-			}
-		}
-		for _, p := range resp.InvalidParameters {
-			if strings.EqualFold(v.parameter, *p) {
-				values[v.parameter] = "VALUE_NOT_EXISTS"
-				c.log.Warning("Value for parameter: %s not exists in AWS Parameter Store. Environment variable: %s", v.parameter, v.envVar)
-			}
-		}
+func (c *DotEnvCommand) printExportEnvVars() {
+	dialect := c.shellDialect()
+	for _, k := range sortedKeys(c.dotEnvMap) {
+		c.log.Printf("%s", dialect.export(k, c.dotEnvMap[k]))
 	}
+}
 
-	return values, nil
+func (c *DotEnvCommand) shellDialect() shellDialect {
+	dialect := shellDialects[c.shell]
+	c.log.must(dialect.validate(c.shell, c.dotEnvMap, c.exportVars))
+	return dialect
 }
 
 func (c *DotEnvCommand) parameter(k, v string) (*string, error) {
 	b := new(bytes.Buffer)
-	if err := c.template.Execute(b, struct{ Name, Value string }{k, v}); err != nil {
+	data := templateData{Name: k, Value: v, Environment: c.environment, Project: c.project}
+	if err := c.template.Execute(b, data); err != nil {
 		return nil, err
 	}
 
@@ -289,20 +421,45 @@ func (c *DotEnvCommand) parameter(k, v string) (*string, error) {
 	return nil, nil
 }
 
-func (c *DotEnvCommand) parseTemplate(templateText string) *template.Template {
-	t, err := template.New("template").Funcs(templateFuncs).Parse(templateText)
-	c.log.must(err)
-	return t
+func (c *DotEnvCommand) newTemplate(templateText string) (*template.Template, error) {
+	return template.New("template").Funcs(templateFuncs).Funcs(c.templateFuncs()).Parse(templateText)
 }
 
 func (c *DotEnvCommand) writeDotEnv(dotEnvFile string, dotEnvMap map[string]string) {
-	err := godotenv.Write(dotEnvMap, GetFullPath(c.app.projectPath, dotEnvFile))
+	content, err := formatDotEnv(c, dotEnvMap)
 	c.log.must(err)
+	c.app.createOrPopulateFile(c.resolvePath(dotEnvFile), content)
 	c.log.Info("Successful.")
 }
 
+// readDotEnv reads vars of dotEnv file, values are interpolated by other vars and process environment
 func (c *DotEnvCommand) readDotEnv(dotEnvFile string) map[string]string {
-	e, err := godotenv.Read(dotEnvFile)
+	lines, err := readDotEnvLines(dotEnvFile)
 	c.log.must(err)
-	return e
+
+	return c.interpolatedValues(lines)
+}
+
+// readDotEnvReferences reads merged vars of source layers, values are interpolated like readDotEnv does
+func (c *DotEnvCommand) readDotEnvReferences() map[string]string {
+	lines := c.mergeLayers()
+	c.interpolated = dotEnvInterpolated(lines)
+
+	return c.interpolatedValues(lines)
+}
+
+func (c *DotEnvCommand) interpolatedValues(lines []dotEnvLine) map[string]string {
+	vars := dotEnvValues(lines)
+	c.log.must(newInterpolator(c.log, vars, dotEnvInterpolated(lines), nil).expandAll())
+
+	return vars
+}
+
+// readDotEnvSource reads merged vars of source layers as is, those are interpolated after references are resolved
+func (c *DotEnvCommand) readDotEnvSource() {
+	lines := c.mergeLayers()
+
+	c.dotEnvLines = lines
+	c.dotEnvMap = dotEnvValues(lines)
+	c.interpolated = dotEnvInterpolated(lines)
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func (c *DotEnvCommand) configureCache(app *kingpin.Application) {
+	cache := app.Command("dotenv-cache", "Manage local cache of resolved Parameter Store values")
+
+	cache.Command("clear", "Remove every cached value").
+		Action(c.runCacheClear)
+}
+
+func (c *DotEnvCommand) runCacheClear(context *kingpin.ParseContext) error {
+	c.log.must(clearCache())
+	c.log.Info("Cache has been cleared")
+
+	return nil
+}
+
+// openCache scopes the cache by region and account of the session, cache is skipped if it cant be opened
+func (c *DotEnvCommand) openCache(awsSession *session.Session) *secretCache {
+	identity, err := sts.New(awsSession).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		c.log.Warning("Cache is disabled, AWS account cant be determined: %v", err)
+		return nil
+	}
+
+	cache, err := newSecretCache(&c.cache, aws.StringValue(awsSession.Config.Region), aws.StringValue(identity.Account))
+	if err != nil {
+		c.log.Warning("Cache is disabled: %v", err)
+		return nil
+	}
+
+	c.log.Debug("Cache: %s, TTL: %s", cache.dir, c.cache.ttl)
+
+	return cache
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	diffMissing      = "missing"
+	diffEqual        = "equal"
+	diffDiffers      = "differs"
+	diffMissingLocal = "missing locally"
+	diffNotCompared  = "-"
+)
+
+type diffOptions struct {
+	dotEnvFile string
+}
+
+func (c *DotEnvCommand) configureDiff(app *kingpin.Application) {
+	cmd := app.Command("dotenv-diff", "Resolve references of .env.<environment> and compare those with local dotEnv file, values are masked").
+		PreAction(c.validateDiff).
+		Action(c.runDiff)
+
+	cmd.Arg("environment", "Environment name").
+		Required().
+		StringVar(&c.environment)
+
+	cmd.Arg("dotEnvFile", "Local dotEnv file to compare resolved values with").
+		StringVar(&c.diff.dotEnvFile)
+
+	c.configureTemplateFlags(cmd)
+	c.configureAwsFlags(cmd)
+	c.configureLayerFlags(cmd)
+	c.configureProviderFlags(cmd)
+	c.configureResolveFlags(cmd)
+}
+
+func (c *DotEnvCommand) validateDiff(context *kingpin.ParseContext) error {
+	c.app.ValidatePath()
+
+	c.log.ShowOpts("Environment", c.environment)
+	if err, isValid := ValidateEnvironment(c.environment); !isValid {
+		c.log.ErrorFWithUsage("%s", err)
+	}
+	c.dotEnvFileSource = c.dotEnvFilePrefix + c.environment
+
+	c.log.ShowOpts("References dotEnv file", c.dotEnvFileSource)
+	if isExists, _ := ValidateFile(GetFullPath(c.app.projectPath, c.dotEnvFileSource)); !isExists {
+		c.log.ErrorFWithUsage("dotEnv file: '%s' does'nt exists", c.dotEnvFileSource)
+	}
+
+	if c.diff.dotEnvFile != "" {
+		c.log.ShowOpts("Local dotEnv file", c.diff.dotEnvFile)
+		if isExists, _ := ValidateFile(GetFullPath(c.app.projectPath, c.diff.dotEnvFile)); !isExists {
+			c.log.ErrorFWithUsage("dotEnv file: '%s' does'nt exists", c.diff.dotEnvFile)
+		}
+	}
+
+	c.layerFiles = c.sourceLayers(c.diff.dotEnvFile)
+
+	return nil
+}
+
+func (c *DotEnvCommand) runDiff(context *kingpin.ParseContext) error {
+	c.template = c.referenceTemplate()
+	c.decrypt = true
+
+	refs := c.readDotEnvReferences()
+	c.log.must(c.rejectParameterPaths("dotenv-diff", refs))
+
+	var local map[string]string
+	if c.diff.dotEnvFile != "" {
+		local = c.readDotEnv(GetFullPath(c.app.projectPath, c.diff.dotEnvFile))
+	}
+
+	if c.refersToAws(refs) {
+		c.initAwsClients()
+	}
+	c.registerProviders()
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	resolved, err := c.resolveReferences(ctx, refs)
+	c.log.must(err)
+
+	// hashes are shown only by the shared key, so those can be compared across machines
+	hasher, err := newValueHasher()
+	if err != nil {
+		c.log.Warning("Value hashes are not shown: %s", err)
+	}
+
+	missing, differs := writeDiffTable(c.log.ioWriter, resolved, local, hasher)
+
+	if missing > 0 || differs > 0 {
+		c.log.ErrorF("References: %d, missing: %d, different: %d", len(resolved), missing, differs)
+	}
+	c.log.Info("References: %d, everything is up to date", len(resolved))
+
+	return nil
+}
+
+// writeDiffTable writes status of every resolved var, values are never shown, only keyed hashes of those
+// if the hasher is given. Missing and different vars are counted
+func writeDiffTable(w io.Writer, resolved []resolvedVar, local map[string]string, hasher *valueHasher) (missing int, differs int) {
+	columns := []string{"VARIABLE", "REFERENCE", "VERSION", "LAST MODIFIED"}
+	if hasher != nil {
+		columns = append(columns, "REMOTE", "LOCAL")
+	}
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(append(columns, "STATUS"), "\t"))
+
+	for _, r := range resolved {
+		version, lastModified := "-", "-"
+		if r.metadata != nil {
+			version = fmt.Sprintf("%d", r.metadata.Version)
+			lastModified = r.metadata.LastModified.UTC().Format(time.RFC3339)
+		}
+		row := []string{r.envVar, r.scheme + schemeSeparator + r.ref, version, lastModified}
+
+		if hasher != nil {
+			remoteHash, localHash := "-", "-"
+			if r.isFound {
+				remoteHash = hasher.hash(r.value)
+			}
+			if localValue, isLocal := local[r.envVar]; isLocal {
+				localHash = hasher.hash(localValue)
+			}
+			row = append(row, remoteHash, localHash)
+		}
+
+		status := diffStatus(r, local)
+		switch status {
+		case diffMissing:
+			missing++
+		case diffMissingLocal, diffDiffers:
+			differs++
+		}
+
+		fmt.Fprintln(table, strings.Join(append(row, status), "\t"))
+	}
+	table.Flush()
+
+	return missing, differs
+}
+
+// diffStatus compares resolved value with local one, nothing is compared without local dotEnv file
+func diffStatus(r resolvedVar, local map[string]string) string {
+	localValue, isLocal := local[r.envVar]
+
+	switch {
+	case !r.isFound:
+		return diffMissing
+	case local == nil:
+		return diffNotCompared
+	case !isLocal:
+		return diffMissingLocal
+	case localValue != r.value:
+		return diffDiffers
+	}
+	return diffEqual
+}
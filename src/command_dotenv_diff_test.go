@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffStatus(t *testing.T) {
+	local := map[string]string{
+		"EQUAL":   "value",
+		"DIFFERS": "old",
+		"MISSING": "value",
+	}
+
+	tests := []struct {
+		name  string
+		r     resolvedVar
+		local map[string]string
+		want  string
+	}{
+		{"equal", resolvedVar{envVar: "EQUAL", value: "value", isFound: true}, local, diffEqual},
+		{"differs", resolvedVar{envVar: "DIFFERS", value: "new", isFound: true}, local, diffDiffers},
+		{"missing", resolvedVar{envVar: "MISSING"}, local, diffMissing},
+		{"missing locally", resolvedVar{envVar: "NEW", value: "value", isFound: true}, local, diffMissingLocal},
+		{"empty local value", resolvedVar{envVar: "EMPTY", value: "value", isFound: true}, map[string]string{"EMPTY": ""}, diffDiffers},
+		{"not compared without local file", resolvedVar{envVar: "EQUAL", value: "value", isFound: true}, nil, diffNotCompared},
+		{"missing without local file", resolvedVar{envVar: "MISSING"}, nil, diffMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffStatus(tt.r, tt.local); got != tt.want {
+				t.Errorf("diffStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDiffTable(t *testing.T) {
+	t.Setenv(HashKeyEnvVar, "team key")
+	hasher, err := newValueHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := []resolvedVar{
+		{envVar: "EQUAL", scheme: ssmScheme, ref: "/app/equal", value: "value", isFound: true},
+		{envVar: "DIFFERS", scheme: ssmScheme, ref: "/app/differs", value: "new", isFound: true},
+		{envVar: "MISSING", scheme: ssmScheme, ref: "/app/missing"},
+	}
+	local := map[string]string{"EQUAL": "value", "DIFFERS": "old"}
+
+	tests := []struct {
+		name   string
+		hasher *valueHasher
+		hashes []string
+	}{
+		{"hashes by the shared key", hasher, []string{hasher.hash("value"), hasher.hash("new"), hasher.hash("old")}},
+		{"no hashes without the shared key", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			missing, differs := writeDiffTable(out, resolved, local, tt.hasher)
+
+			if missing != 1 || differs != 1 {
+				t.Errorf("missing, differs = %d, %d, want 1, 1", missing, differs)
+			}
+			if got := strings.Contains(out.String(), "REMOTE"); got != (tt.hasher != nil) {
+				t.Errorf("hash columns shown = %v:\n%s", got, out)
+			}
+			if got := strings.Contains(out.String(), hashPrefix); got != (len(tt.hashes) > 0) {
+				t.Errorf("hashes shown = %v:\n%s", got, out)
+			}
+			for _, hash := range tt.hashes {
+				if !strings.Contains(out.String(), hash) {
+					t.Errorf("hash %s is not shown:\n%s", hash, out)
+				}
+			}
+			for _, value := range []string{"value", "new", "old"} {
+				if strings.Contains(out.String(), " "+value+" ") {
+					t.Errorf("value %q is shown:\n%s", value, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sort"
+	"strings"
+)
+
+const (
+	pushCreate    = "create"
+	pushUpdate    = "update"
+	pushUnchanged = "unchanged"
+)
+
+type pushOptions struct {
+	dotEnvFile    string
+	parameterType string
+	kmsKeyId      string
+	overwrite     bool
+}
+
+// pushItem is a planned change of a single parameter
+type pushItem struct {
+	// envVar is the comma separated list of vars referring to the parameter
+	envVar    string
+	parameter string
+	value     string
+	action    string
+}
+
+func (c *DotEnvCommand) configurePush(app *kingpin.Application) {
+	cmd := app.Command("dotenv-push", "Push values of filled dotEnv file into Parameter Store by 'ssm://' references of .env.<environment>").
+		PreAction(c.validatePush).
+		Action(c.runPush)
+
+	cmd.Arg("environment", "Environment name, .env.<environment> contains 'ssm://' references").
+		Required().
+		StringVar(&c.environment)
+
+	cmd.Arg("dotEnvFile", "Filled dotEnv file which values will be pushed").
+		Required().
+		StringVar(&c.push.dotEnvFile)
+
+	cmd.Flag("type", "Parameter type, one of: SecureString, String").
+		Default(ssm.ParameterTypeSecureString).
+		EnumVar(&c.push.parameterType, ssm.ParameterTypeSecureString, ssm.ParameterTypeString)
+
+	cmd.Flag("kms-key-id", "KMS key ID, ARN or alias for SecureString parameters, default: AWS managed key").
+		PlaceHolder("KEY").
+		StringVar(&c.push.kmsKeyId)
+
+	cmd.Flag("overwrite", "Update existing parameters which values differ, otherwise only new parameters will be created").
+		Default("false").
+		BoolVar(&c.push.overwrite)
+
+	c.configureTemplateFlags(cmd)
+	c.configureAwsFlags(cmd)
+	c.configureLayerFlags(cmd)
+}
+
+func (c *DotEnvCommand) validatePush(context *kingpin.ParseContext) error {
+	c.app.ValidatePath()
+
+	c.log.ShowOpts("Environment", c.environment)
+	if err, isValid := ValidateEnvironment(c.environment); !isValid {
+		c.log.ErrorFWithUsage("%s", err)
+	}
+	c.dotEnvFileSource = c.dotEnvFilePrefix + c.environment
+
+	c.log.ShowOpts("References dotEnv file", c.dotEnvFileSource)
+	if isExists, _ := ValidateFile(c.resolvePath(c.dotEnvFileSource)); !isExists {
+		c.log.ErrorFWithUsage("dotEnv file: '%s' does'nt exists", c.dotEnvFileSource)
+	}
+
+	c.log.ShowOpts("Values dotEnv file", c.push.dotEnvFile)
+	if isExists, _ := ValidateFile(c.resolvePath(c.push.dotEnvFile)); !isExists {
+		c.log.ErrorFWithUsage("dotEnv file: '%s' does'nt exists", c.push.dotEnvFile)
+	}
+
+	if strings.EqualFold(c.dotEnvFileSource, c.push.dotEnvFile) {
+		c.log.ErrorF("References dotEnv file '%s' and values dotEnv file '%s' must be different", c.dotEnvFileSource, c.push.dotEnvFile)
+	}
+
+	c.layerFiles = c.sourceLayers(c.push.dotEnvFile)
+
+	return nil
+}
+
+func (c *DotEnvCommand) runPush(context *kingpin.ParseContext) error {
+	c.template = c.referenceTemplate()
+
+	refs := c.readDotEnvReferences()
+	c.log.must(c.rejectParameterPaths("dotenv-push", refs))
+	values := c.readPushValues()
+
+	c.initAwsClients()
+
+	plan, err := c.pushPlan(refs, values)
+	c.log.must(err)
+
+	var changes []pushItem
+	for _, item := range plan {
+		switch {
+		case item.action == pushUpdate && !c.push.overwrite:
+			c.log.Warning("%-10s %s => %s, exists and differs, use --overwrite to update it", item.action, item.envVar, item.parameter)
+		case item.action == pushUnchanged:
+			c.log.Info("%-10s %s => %s", item.action, item.envVar, item.parameter)
+		default:
+			c.log.Info("%-10s %s => %s", item.action, item.envVar, item.parameter)
+			changes = append(changes, item)
+		}
+	}
+
+	if len(changes) == 0 {
+		c.log.Info("Nothing to push.")
+		return nil
+	}
+
+	c.app.AskConfirmOrSkip(c.app.isCi)
+
+	for _, item := range changes {
+		c.putParameter(item)
+	}
+	c.log.Info("Successful.")
+
+	return nil
+}
+
+// readPushValues reads values of the filled dotEnv file as written, those are never interpolated
+func (c *DotEnvCommand) readPushValues() map[string]string {
+	lines, err := readDotEnvLines(c.resolvePath(c.push.dotEnvFile))
+	c.log.must(err)
+	return dotEnvLiteralValues(lines)
+}
+
+// pushPlan compares values with current parameters, vars without SSM references are ignored.
+// References with modifiers or version selectors are skipped, the value cant be pushed as is to those.
+// Vars referring to the same parameter are planned once, those must have the same value
+func (c *DotEnvCommand) pushPlan(refs map[string]string, values map[string]string) ([]pushItem, error) {
+	var plan []pushItem
+	planned := make(map[string]int)
+
+	for _, k := range sortedKeys(refs) {
+		parameter, err := c.parameter(k, refs[k])
+		c.log.must(err)
+		// user template may not handle 'ssm://' references
+		if parameter == nil && strings.HasPrefix(refs[k], ssmScheme+schemeSeparator) {
+			name := strings.TrimPrefix(refs[k], ssmScheme+schemeSeparator)
+			parameter = &name
+		}
+		if parameter == nil {
+			continue
+		}
+		if strings.Contains(*parameter, "?") {
+			c.log.Warning("Environment variable: %s refers to '%s' with modifiers and will be skipped", k, *parameter)
+			continue
+		}
+		if _, selector := ssmSelector(*parameter); selector != "" {
+			c.log.Warning("Environment variable: %s refers to '%s' pinned to version or label and will be skipped", k, *parameter)
+			continue
+		}
+
+		value, ok := values[k]
+		if !ok {
+			c.log.Warning("Environment variable: %s not exists in '%s' and will be skipped", k, c.push.dotEnvFile)
+			continue
+		}
+		if value == valueNotExists {
+			c.log.Warning("Environment variable: %s has no value in '%s' and will be skipped", k, c.push.dotEnvFile)
+			continue
+		}
+
+		if i, ok := planned[*parameter]; ok {
+			if plan[i].value != value {
+				return nil, fmt.Errorf("environment variables %s and %s refer to '%s' with different values", plan[i].envVar, k, *parameter)
+			}
+			plan[i].envVar += ", " + k
+			continue
+		}
+
+		planned[*parameter] = len(plan)
+		plan = append(plan, pushItem{envVar: k, parameter: *parameter, value: value})
+	}
+
+	names := make([]string, 0, len(plan))
+	for _, item := range plan {
+		names = append(names, item.parameter)
+	}
+	current := c.currentParameters(names)
+
+	for i, item := range plan {
+		value, exists := current[item.parameter]
+		switch {
+		case !exists:
+			plan[i].action = pushCreate
+		case value != item.value:
+			plan[i].action = pushUpdate
+		default:
+			plan[i].action = pushUnchanged
+		}
+	}
+
+	return plan, nil
+}
+
+// currentParameters returns decrypted values of existing parameters
+func (c *DotEnvCommand) currentParameters(names []string) map[string]string {
+	values := make(map[string]string)
+
+	sort.Strings(names)
+	for i := 0; i < len(names); i += c.batchSize {
+		j := i + c.batchSize
+		if j > len(names) {
+			j = len(names)
+		}
+
+		input := &ssm.GetParametersInput{
+			Names:          aws.StringSlice(names[i:j]),
+			WithDecryption: aws.Bool(true),
+		}
+
+		var resp *ssm.GetParametersOutput
+		err := c.withRetry(aws.BackgroundContext(), "Current values", func() (err error) {
+			resp, err = c.ssm.GetParametersWithContext(aws.BackgroundContext(), input)
+			return err
+		})
+		c.log.must(err)
+
+		for _, p := range resp.Parameters {
+			values[*p.Name] = *p.Value
+		}
+	}
+
+	return values
+}
+
+func (c *DotEnvCommand) putParameter(item pushItem) {
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(item.parameter),
+		Value:     aws.String(item.value),
+		Type:      aws.String(c.push.parameterType),
+		Overwrite: aws.Bool(item.action == pushUpdate),
+	}
+	if c.push.kmsKeyId != "" && c.push.parameterType == ssm.ParameterTypeSecureString {
+		input.KeyId = aws.String(c.push.kmsKeyId)
+	}
+
+	var resp *ssm.PutParameterOutput
+	err := c.withRetry(aws.BackgroundContext(), "Parameter: "+item.parameter, func() (err error) {
+		resp, err = c.ssm.PutParameterWithContext(aws.BackgroundContext(), input)
+		return err
+	})
+	c.log.must(err)
+	c.log.Debug("Parameter: %s, version: %v", item.parameter, aws.Int64Value(resp.Version))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPushPlan(t *testing.T) {
+	client := &fakeSsm{parameters: map[string]string{
+		"/app/same":    "same",
+		"/app/changed": "old",
+		"/app/key":     "key",
+	}}
+
+	c := testDotEnvCommand(t)
+	c.ssm = client
+	c.push.dotEnvFile = ".env.dev.values"
+
+	refs := map[string]string{
+		"SAME":       "ssm:///app/same",
+		"SAME_ALIAS": "ssm:///app/same",
+		"CHANGED":    "ssm:///app/changed",
+		"NEW":        "ssm:///app/new",
+		"CERT":       "ssm:///app/cert?decode=base64",
+		"KEY":        "ssm:///app/key:3",
+		"LABELED":    "ssm:///app/key:stable",
+		"MISSING":    "ssm:///app/missing",
+		"NOT_EXISTS": "ssm:///app/not_exists",
+		"PLAIN":      "plain",
+		"VAULT":      "vault://secret/app#key",
+	}
+	values := map[string]string{
+		"SAME":       "same",
+		"SAME_ALIAS": "same",
+		"CHANGED":    "new",
+		"NEW":        "new",
+		"CERT":       "cert",
+		"KEY":        "key",
+		"LABELED":    "key",
+		"NOT_EXISTS": valueNotExists,
+		"PLAIN":      "plain",
+		"VAULT":      "vault",
+	}
+
+	got, err := c.pushPlan(refs, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pushItem{
+		{envVar: "CHANGED", parameter: "/app/changed", value: "new", action: pushUpdate},
+		{envVar: "NEW", parameter: "/app/new", value: "new", action: pushCreate},
+		{envVar: "SAME, SAME_ALIAS", parameter: "/app/same", value: "same", action: pushUnchanged},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pushPlan() = %+v, want %+v", got, want)
+	}
+
+	for _, name := range client.requested {
+		if strings.ContainsAny(name, "?:") {
+			t.Errorf("requested parameter name %q contains modifiers or selector", name)
+		}
+	}
+}
+
+func TestPushPlanConflictingValues(t *testing.T) {
+	client := &fakeSsm{}
+	c := testDotEnvCommand(t)
+	c.ssm = client
+	c.push.dotEnvFile = ".env.dev.values"
+
+	refs := map[string]string{
+		"DB_PASSWORD":       "ssm:///app/db/password",
+		"MIGRATOR_PASSWORD": "ssm:///app/db/password",
+	}
+	values := map[string]string{
+		"DB_PASSWORD":       "first",
+		"MIGRATOR_PASSWORD": "second",
+	}
+
+	if _, err := c.pushPlan(refs, values); err == nil || !strings.Contains(err.Error(), "/app/db/password") {
+		t.Errorf("pushPlan() error = %v, conflicting values error is expected", err)
+	}
+	if len(client.requested) != 0 {
+		t.Errorf("requested parameters = %v, plan must fail before any request", client.requested)
+	}
+}
+
+func TestReadPushValues(t *testing.T) {
+	c := testDotEnvCommand(t)
+	// values file can be outside of the project
+	c.push.dotEnvFile = filepath.Join(t.TempDir(), ".env.dev.values")
+	content := strings.Join([]string{
+		`DB_PASSWORD="pa$$word"`,
+		`API_TOKEN=t0k$en`,
+		`LOWER=$lowercase`,
+		`BRACES="${HOME}"`,
+		`GENERATED="pa\$s"`,
+		`LITERAL='pa$s'`,
+	}, "\n")
+	if err := os.WriteFile(c.push.dotEnvFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB_PASSWORD": "pa$$word",
+		"API_TOKEN":   "t0k$en",
+		"LOWER":       "$lowercase",
+		"BRACES":      "${HOME}",
+		"GENERATED":   "pa$s",
+		"LITERAL":     "pa$s",
+	}
+	if got := c.readPushValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("readPushValues() = %q, want %q", got, want)
+	}
+}
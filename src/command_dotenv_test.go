@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDotEnvCommand is the command of a project in temporary dir, logs are discarded
+func testDotEnvCommand(t *testing.T) *DotEnvCommand {
+	t.Helper()
+
+	log := &Log{ioWriter: io.Discard}
+	app := &App{log: log, projectPath: t.TempDir()}
+
+	c := &DotEnvCommand{
+		app:              app,
+		log:              log,
+		environment:      "dev",
+		dotEnvFilePrefix: defaultDotEnvFilePrefix,
+		dotEnvFileSource: defaultDotEnvFilePrefix + "dev",
+		decrypt:          true,
+		batchSize:        defaultBatchSize,
+		concurrency:      1,
+	}
+	template, err := c.newTemplate(defaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.template = template
+	return c
+}
+
+// withSource sets the source of the command as it's read from dotEnv file
+func withSource(t *testing.T, c *DotEnvCommand, content string) *DotEnvCommand {
+	t.Helper()
+
+	lines, err := parseDotEnv(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.dotEnvLines = lines
+	c.dotEnvMap = dotEnvValues(lines)
+	c.interpolated = dotEnvInterpolated(lines)
+	return c
+}
+
+func TestDotEnvCommandLine(t *testing.T) {
+	tests := []struct {
+		args        []string
+		command     string
+		environment string
+	}{
+		{[]string{"dotenv", "dev"}, "dotenv", "dev"},
+		{[]string{"dotenv", "dev", "-e"}, "dotenv", "dev"},
+		{[]string{"dotenv", "-e", "dev"}, "dotenv", "dev"},
+		{[]string{"dotenv", "dev", ".env"}, "dotenv", "dev"},
+		{[]string{"-p", "/tmp", "dotenv", "dev", "--exec", "--", "env"}, "dotenv", "dev"},
+		{[]string{"dotenv", "dev", "-f", "json", "--layer", ".env.ci"}, "dotenv", "dev"},
+		{[]string{"dotenv-push", "dev", ".env.dev.values", "--overwrite"}, "dotenv-push", "dev"},
+		{[]string{"dotenv", "dev", "--provider", "file", "--provider", "cmd"}, "dotenv", "dev"},
+		{[]string{"dotenv-diff", "dev"}, "dotenv-diff", "dev"},
+		{[]string{"dotenv-diff", "dev", "--provider", "vault"}, "dotenv-diff", "dev"},
+		{[]string{"dotenv-diff", "dev", ".env.dev.values", "--timeout", "30s", "--concurrency", "2"}, "dotenv-diff", "dev"},
+		{[]string{"dotenv-cache", "clear"}, "dotenv-cache clear", ""},
+	}
+
+	for _, tt := range tests {
+		context, err := NewApp(tt.args).cli.ParseContext(tt.args)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if got := context.SelectedCommand.FullCommand(); got != tt.command {
+			t.Errorf("%v: command = %q, want %q", tt.args, got, tt.command)
+		}
+
+		environment := ""
+		for _, element := range context.Elements {
+			if arg, ok := element.Clause.(*kingpin.ArgClause); ok && arg.Model().Name == "environment" {
+				environment = *element.Value
+			}
+		}
+		if environment != tt.environment {
+			t.Errorf("%v: environment = %q, want %q", tt.args, environment, tt.environment)
+		}
+	}
+}
+
+func TestStrictFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		ci   string
+		args []string
+		want bool
+	}{
+		{"off by default", "", nil, false},
+		{"CI turns it on", "true", nil, true},
+		{"CI is false", "false", nil, false},
+		{"no-strict flag in CI", "true", []string{"--no-strict"}, false},
+		{"strict flag", "", []string{"--strict"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ci == "" {
+				t.Setenv(CiEnvVar, "")
+				os.Unsetenv(CiEnvVar)
+			} else {
+				t.Setenv(CiEnvVar, tt.ci)
+			}
+
+			c := testDotEnvCommand(t)
+			app := kingpin.New("tfconfig", "")
+			c.configureStrictFlag(app.Command("dotenv", ""))
+
+			if _, err := app.Parse(append([]string{"dotenv"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if c.strict != tt.want {
+				t.Errorf("strict = %v, want %v", c.strict, tt.want)
+			}
+		})
+	}
+}
+
+// TestStrictModeWritesNoOutput runs dotenv in a child test process, since failed strict mode exits
+func TestStrictModeWritesNoOutput(t *testing.T) {
+	if projectPath := os.Getenv("TFCONFIG_TEST_STRICT"); projectPath != "" {
+		c := testDotEnvCommand(t)
+		c.log.ioWriter = os.Stderr
+		c.app.projectPath = projectPath
+		c.layerFiles = []string{c.dotEnvFileSource}
+		c.enabledProviders = []string{envScheme}
+		c.strict = true
+		c.exposeVars = false
+		c.dotEnvFileOut = ".env"
+		c.run(nil)
+		return
+	}
+
+	projectPath := t.TempDir()
+	source := "DB_HOST=db1\nDB_USER=env://TFCONFIG_TEST_MISSING_USER\nDB_PASSWORD=env://TFCONFIG_TEST_MISSING_PASSWORD\n"
+	if err := os.WriteFile(filepath.Join(projectPath, defaultDotEnvFilePrefix+"dev"), []byte(source), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStrictModeWritesNoOutput$")
+	cmd.Env = append(os.Environ(), "TFCONFIG_TEST_STRICT="+projectPath)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("run() error = %v, want exit code 1, stderr:\n%s", err, stderr)
+	}
+	for _, k := range []string{"DB_USER", "DB_PASSWORD"} {
+		if !strings.Contains(stderr.String(), k) {
+			t.Errorf("error doesn't name %s:\n%s", k, stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, ".env")); !os.IsNotExist(err) {
+		t.Errorf("dotEnv file is written in failed strict mode, stat error = %v", err)
+	}
+}
@@ -150,6 +150,7 @@ type EnvCommand struct {
 	projectConfigPath     string
 	template              *template.Template
 	dotEnvConfig          EnvironmentDotEnv
+	generate              GenerateOptions
 }
 
 func ConfigureEnvCommand(a *App) {
@@ -171,6 +172,8 @@ func ConfigureEnvCommand(a *App) {
 		Short('l').
 		Envar(TerraformLocalEnvVar).
 		BoolVar(&c.local)
+
+	ConfigureGenerateFlags(cmd, &c.generate)
 }
 
 func (c *EnvCommand) run(context *kingpin.ParseContext) error {
@@ -184,11 +187,18 @@ func (c *EnvCommand) run(context *kingpin.ParseContext) error {
 
 	c.projectConfig = c.dotEnvMapper(&c.dotEnvConfig)
 
+	// TODO move under normalized path resolving
+	environmentFile := GetFullPath(c.app.projectPath, EnvironmentFile)
+	content := c.executeTemplate(c.template, c.projectConfig)
+
+	if !c.app.PreviewGenerated(environmentFile, content, &c.generate) {
+		return nil
+	}
+
 	c.app.AskConfirmOrSkip(c.app.isCi)
 
-	// TODO move under normalized path resolving
-	if c.app.createOrPopulateFile(GetFullPath(c.app.projectPath, EnvironmentFile), c.executeTemplate(c.template, c.projectConfig)) {
-		c.log.Info("Successfully generated: %s", filepath.Base(GetFullPath(c.app.projectPath, EnvironmentFile)))
+	if c.app.createOrPopulateFile(environmentFile, content) {
+		c.log.Info("Successfully generated: %s", filepath.Base(environmentFile))
 	} else {
 		c.log.ErrorF("I don't really know what exactly should be happen to cause that error ¯\\_(ツ)_/¯ ")
 	}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultRoleSessionName = "tfconfig"
+
+type awsOptions struct {
+	region          string
+	profile         string
+	roleArn         string
+	externalId      string
+	roleSessionName string
+	mfaSerial       string
+	endpointUrl     string
+}
+
+func (c *DotEnvCommand) configureAwsFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("region", "AWS region, default: REGION of the environment config, otherwise AWS_REGION or region of the profile").
+		PlaceHolder("REGION").
+		StringVar(&c.aws.region)
+
+	cmd.Flag("profile", "AWS shared config profile, default: AWS_PROFILE").
+		PlaceHolder("PROFILE").
+		StringVar(&c.aws.profile)
+
+	cmd.Flag("role-arn", "ARN of IAM role that will be assumed").
+		PlaceHolder("ARN").
+		StringVar(&c.aws.roleArn)
+
+	cmd.Flag("external-id", "External ID of the assumed role").
+		StringVar(&c.aws.externalId)
+
+	cmd.Flag("role-session-name", "Session name of the assumed role").
+		Default(defaultRoleSessionName).
+		StringVar(&c.aws.roleSessionName)
+
+	cmd.Flag("mfa-serial", "MFA device serial number or ARN, MFA token code will be prompted for the assumed role").
+		PlaceHolder("SERIAL").
+		StringVar(&c.aws.mfaSerial)
+
+	cmd.Flag("endpoint-url", "Custom AWS endpoint, like 'http://localhost:4566' for LocalStack").
+		PlaceHolder("URL").
+		StringVar(&c.aws.endpointUrl)
+}
+
+// awsSession is created by the profile and assumes the role if it's specified
+func (c *DotEnvCommand) awsSession() *session.Session {
+	awsConfig := &aws.Config{
+		LogLevel: aws.LogLevel(aws.LogOff),
+		Logger:   aws.LoggerFunc(c.log.AwsLog),
+	}
+
+	if c.log.awsDebug {
+		awsConfig.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+	}
+
+	if region := c.awsRegion(); region != "" {
+		awsConfig.Region = aws.String(region)
+	}
+
+	if c.aws.endpointUrl != "" {
+		c.log.ShowOpts("AWS endpoint", c.aws.endpointUrl)
+		awsConfig.Endpoint = aws.String(c.aws.endpointUrl)
+	}
+
+	awsSession, err := session.NewSessionWithOptions(session.Options{
+		Config:                  *awsConfig,
+		Profile:                 c.aws.profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: mfaTokenProvider,
+	})
+	c.log.must(err)
+
+	if c.aws.roleArn == "" {
+		return awsSession
+	}
+
+	c.log.ShowOpts("AWS role", c.aws.roleArn)
+	credentials := stscreds.NewCredentials(awsSession, c.aws.roleArn, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = c.aws.roleSessionName
+		if c.aws.externalId != "" {
+			p.ExternalID = aws.String(c.aws.externalId)
+		}
+		if c.aws.mfaSerial != "" {
+			p.SerialNumber = aws.String(c.aws.mfaSerial)
+			p.TokenProvider = mfaTokenProvider
+		}
+	})
+
+	return awsSession.Copy(&aws.Config{Credentials: credentials})
+}
+
+// awsRegion is taken from --region flag or REGION of environment config,
+// otherwise AWS SDK resolves it by AWS_REGION or the profile. AWS_REGION is usually exported in the shell,
+// so it never overrides the region of the environment
+func (c *DotEnvCommand) awsRegion() string {
+	if c.aws.region != "" {
+		c.log.ShowOpts("AWS region", c.aws.region)
+		return c.aws.region
+	}
+
+	environmentConfigPath, isFound := c.environmentConfigPath()
+	if !isFound {
+		return ""
+	}
+
+	region := c.app.ReadDotEnv(environmentConfigPath)["REGION"]
+	if region != "" {
+		c.log.ShowOpts("AWS region", region)
+	}
+	return region
+}
+
+// environmentConfigPath looks for modules dir in the project and parent folders like findModules,
+// but it doesn't log every folder and doesn't fail on unreadable ones, REGION is optional
+func (c *DotEnvCommand) environmentConfigPath() (path string, isFound bool) {
+	modulesDir := ModulesDir
+	if c.app.isNewEnvVersion() {
+		modulesDir = ModulesDirV2
+	}
+
+	for _, v := range listSearchPaths() {
+		modulesPath, _ := filepath.Abs(filepath.Join(c.app.projectPath, v, modulesDir))
+		if info, err := os.Stat(modulesPath); err != nil || !info.IsDir() {
+			continue
+		}
+
+		path = filepath.Join(modulesPath, EnvironmentsDir, c.environment, defaultEnvironmentConfig)
+		isExists, _ := ValidateFile(path)
+		return path, isExists
+	}
+	return "", false
+}
+
+// refersToAws is true if values or 'ssm-path://' lines of the source are resolved by AWS clients,
+// sources of other providers only don't need AWS session, region and credentials
+func (c *DotEnvCommand) refersToAws(values map[string]string) bool {
+	if len(c.pathPrefixes) > 0 {
+		return true
+	}
+
+	for _, l := range c.dotEnvLines {
+		if l.isVar() && l.quote != '\'' && strings.HasPrefix(l.value, ssmPathPrefix) {
+			return true
+		}
+	}
+
+	for _, k := range sortedKeys(values) {
+		v := values[k]
+		parameter, err := c.parameter(k, v)
+		c.log.must(err)
+
+		if parameter != nil {
+			return true
+		}
+		if c.isLiteral(k) {
+			continue
+		}
+		if strings.HasPrefix(v, ssmScheme+schemeSeparator) || strings.HasPrefix(v, secretsManagerScheme+schemeSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// mfaTokenProvider prompts MFA token code on stderr, so it doesn't get into printed vars
+func mfaTokenProvider() (string, error) {
+	fmt.Fprint(os.Stderr, "Assume Role MFA token code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(code), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAwsRegion(t *testing.T) {
+	tests := []struct {
+		name         string
+		flag         string
+		env          string
+		configRegion string
+		projectDir   string
+		want         string
+	}{
+		{"flag", "eu-west-1", "us-east-1", "eu-central-1", "", "eu-west-1"},
+		{"environment config over AWS_REGION", "", "us-east-1", "eu-central-1", "", "eu-central-1"},
+		{"environment config", "", "", "eu-central-1", "", "eu-central-1"},
+		{"environment config of the parent folder", "", "", "eu-central-1", filepath.Join("stacks", "app"), "eu-central-1"},
+		// AWS SDK resolves region by AWS_REGION or the profile
+		{"environment config is missing", "", "us-east-1", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tt.env)
+
+			c := testDotEnvCommand(t)
+			log := new(bytes.Buffer)
+			c.log.ioWriter = log
+			c.aws.region = tt.flag
+			if tt.configRegion != "" {
+				dir := filepath.Join(c.app.projectPath, ModulesDir, EnvironmentsDir, c.environment)
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				config := []byte("REGION=" + tt.configRegion + "\n")
+				if err := os.WriteFile(filepath.Join(dir, defaultEnvironmentConfig), config, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c.app.projectPath = filepath.Join(c.app.projectPath, tt.projectDir)
+			if err := os.MkdirAll(c.app.projectPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.awsRegion(); got != tt.want {
+				t.Errorf("awsRegion() = %q, want %q", got, tt.want)
+			}
+			// folders aren't logged on every dotenv run
+			if strings.Contains(log.String(), "Looking in") {
+				t.Errorf("awsRegion() logs looked up folders:\n%s", log.String())
+			}
+		})
+	}
+}
+
+func TestRefersToAws(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		pathPrefixes []string
+		want         bool
+	}{
+		{"ssm reference", "A=ssm:///app/a\n", nil, true},
+		{"secrets manager reference", "A=secretsmanager://app/db\n", nil, true},
+		{"parameter path", "_=ssm-path:///app/\n", nil, true},
+		{"path prefix flag", "A=1\n", []string{"/app/"}, true},
+		{"other providers only", "A=sops://secrets.enc.yaml#/db\nB=env://HOME\nC=file:///run/secret\n", nil, false},
+		{"literal references", "B='secretsmanager://app/db'\n_='ssm-path:///app/'\n", nil, false},
+		// the template applies to literal values as well
+		{"literal ssm reference of the template", "A='ssm:///app/a'\n", nil, true},
+		{"plain values", "A=1\nB=\"two\"\n", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withSource(t, testDotEnvCommand(t), tt.source)
+			c.pathPrefixes = tt.pathPrefixes
+
+			if got := c.refersToAws(c.dotEnvMap); got != tt.want {
+				t.Errorf("refersToAws() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
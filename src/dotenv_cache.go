@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/pbkdf2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCacheTtl is how long resolved values are kept in the cache
+	defaultCacheTtl = "15m"
+
+	// cacheDirName is created under the user cache dir, like '~/.cache/tfconfig/dotenv'
+	cacheDirName = "tfconfig/dotenv"
+
+	cacheSaltFile         = "salt"
+	cacheKeyringService   = "tfconfig"
+	cacheKeyringUser      = "dotenv-cache"
+	cacheKeyLen           = 32
+	cacheKeyKdfIterations = 600000
+)
+
+type cacheOptions struct {
+	enabled bool
+	ttl     time.Duration
+	keyFile string
+}
+
+// secretCache keeps resolved values encrypted on disk, every entry is a separate file
+type secretCache struct {
+	dir   string
+	scope string
+	ttl   time.Duration
+	aead  cipher.AEAD
+}
+
+type cacheEntry struct {
+	Value    string         `json:"value"`
+	Metadata SecretMetadata `json:"metadata"`
+	Cached   time.Time      `json:"cached"`
+}
+
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheDirName), nil
+}
+
+// newSecretCache opens the cache of values resolved in region by account
+func newSecretCache(o *cacheOptions, region string, account string) (*secretCache, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	key, err := cacheKey(dir, o.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretCache{
+		dir:   dir,
+		scope: region + "/" + account,
+		ttl:   o.ttl,
+		aead:  aead,
+	}, nil
+}
+
+// cacheKey derives the key from passphrase file if it's specified, otherwise the key is kept in OS keyring
+func cacheKey(dir string, keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		passphrase, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(passphrase)) == "" {
+			return nil, fmt.Errorf("cache passphrase file '%s' is empty", keyFile)
+		}
+
+		salt, err := cacheSalt(dir)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key([]byte(strings.TrimSpace(string(passphrase))), salt, cacheKeyKdfIterations, cacheKeyLen, sha256.New), nil
+	}
+
+	encoded, err := keyring.Get(cacheKeyringService, cacheKeyringUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		key, err := randomBytes(cacheKeyLen)
+		if err != nil {
+			return nil, err
+		}
+		if err := keyring.Set(cacheKeyringService, cacheKeyringUser, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("OS keyring is not available, use --cache-key-file instead: %v", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OS keyring is not available, use --cache-key-file instead: %v", err)
+	}
+
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// cacheSalt is generated once per cache dir, clearing the cache generates a new one
+func cacheSalt(dir string) ([]byte, error) {
+	path := filepath.Join(dir, cacheSaltFile)
+
+	salt, err := os.ReadFile(path)
+	if err == nil {
+		return salt, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if salt, err = randomBytes(cacheKeyLen); err != nil {
+		return nil, err
+	}
+	return salt, os.WriteFile(path, salt, 0600)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func clearCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// id is the cache key within scope, it's used as additional data so entries cant be swapped
+func (s *secretCache) id(key string) string {
+	return s.scope + "/" + key
+}
+
+func (s *secretCache) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// get returns entry cached within TTL, unreadable entries are treated as missing and expired ones are removed
+func (s *secretCache) get(key string) (*cacheEntry, bool) {
+	id := s.id(key)
+	path := s.path(id)
+
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) < s.aead.NonceSize() {
+		return nil, false
+	}
+
+	nonce, sealed := content[:s.aead.NonceSize()], content[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(plain, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.Cached) > s.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (s *secretCache) put(key string, value string, metadata SecretMetadata) error {
+	id := s.id(key)
+
+	plain, err := json.Marshal(cacheEntry{Value: value, Metadata: metadata, Cached: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	nonce, err := randomBytes(s.aead.NonceSize())
+	if err != nil {
+		return err
+	}
+
+	// entries are written by concurrent workers, so file is replaced atomically
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(s.aead.Seal(nonce, nonce, plain, []byte(id))); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(id))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyFromPassphraseFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(keyFile, []byte("correct horse battery staple\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	key, err := cacheKey(dir, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != cacheKeyLen {
+		t.Fatalf("len(key) = %d, want %d", len(key), cacheKeyLen)
+	}
+
+	same, err := cacheKey(dir, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, same) {
+		t.Error("key derived twice within the same cache dir differs")
+	}
+
+	// cleared cache dir gets a new salt, so the key differs
+	other, err := cacheKey(t.TempDir(), keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(key, other) {
+		t.Error("key derived with another salt is the same")
+	}
+}
+
+func TestCacheKeyEmptyPassphraseFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(keyFile, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cacheKey(t.TempDir(), keyFile); err == nil {
+		t.Error("cacheKey() error = nil, want empty passphrase error")
+	}
+}
+
+func testSecretCache(t *testing.T, dir string, scope string, ttl time.Duration) *secretCache {
+	t.Helper()
+
+	block, err := aes.NewCipher(bytes.Repeat([]byte{1}, cacheKeyLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &secretCache{dir: dir, scope: scope, ttl: ttl, aead: aead}
+}
+
+func TestSecretCache(t *testing.T) {
+	dir := t.TempDir()
+	s := testSecretCache(t, dir, "eu-west-1/123456789012", time.Minute)
+
+	metadata := SecretMetadata{Version: 3, Type: "SecureString"}
+	if err := s.put("ssm:/app/db/password", "s3cr3t", metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := s.get("ssm:/app/db/password")
+	if !ok {
+		t.Fatal("get() is missing the entry")
+	}
+	if entry.Value != "s3cr3t" || entry.Metadata.Version != 3 {
+		t.Errorf("get() = %+v", entry)
+	}
+
+	content, err := os.ReadFile(s.path(s.id("ssm:/app/db/password")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(content, []byte("s3cr3t")) {
+		t.Error("cached entry is not encrypted")
+	}
+
+	if _, ok := s.get("ssm:/app/api/key"); ok {
+		t.Error("get() of missing key returned entry")
+	}
+
+	// entries of another account are not shared even if file is copied
+	other := testSecretCache(t, dir, "eu-west-1/210987654321", time.Minute)
+	if err := os.WriteFile(other.path(other.id("ssm:/app/db/password")), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := other.get("ssm:/app/db/password"); ok {
+		t.Error("entry of another scope is decrypted")
+	}
+
+	expired := testSecretCache(t, dir, "eu-west-1/123456789012", -time.Second)
+	if _, ok := expired.get("ssm:/app/db/password"); ok {
+		t.Error("expired entry is returned")
+	}
+	if _, err := os.Stat(s.path(s.id("ssm:/app/db/password"))); !os.IsNotExist(err) {
+		t.Errorf("expired entry is not removed: %v", err)
+	}
+	if _, ok := s.get("ssm:/app/db/password"); ok {
+		t.Error("removed entry is returned")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	formatGithubEnv    = "github-env"
+	formatGitlabDotEnv = "gitlab-dotenv"
+	formatAzure        = "azure-devops"
+
+	// GithubEnvFileVar is the file which vars of the next steps are appended to
+	GithubEnvFileVar = "GITHUB_ENV"
+)
+
+// GitLab dotenv report accepts only letters, digits and underscores in var names
+var gitlabVarName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Azure DevOps logging commands are unescaped by the agent
+var azureDataEscaper = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A")
+var azurePropertyEscaper = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", "]", "%5D", ";", "%3B")
+
+// appendedFormats are appended to the destination file, other ones replace it
+var appendedFormats = map[string]bool{
+	formatGithubEnv: true,
+}
+
+// githubEnvFile is the destination of 'github-env' format if dotEnvFile is omitted,
+// vars are printed if it's run outside of GitHub Actions
+func (c *DotEnvCommand) githubEnvFile() string {
+	if c.format != formatGithubEnv || c.dotEnvFileOut != "" {
+		return c.dotEnvFileOut
+	}
+	return os.Getenv(GithubEnvFileVar)
+}
+
+// formatGithubEnvFile writes every var with heredoc syntax 'KEY<<DELIMITER', so multiline values are kept,
+// the delimiter is random and must not be a line of the value
+func formatGithubEnvFile(c *DotEnvCommand, vars map[string]string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	delimiter := "ghadelimiter_" + hex.EncodeToString(b)
+
+	var out strings.Builder
+	for _, k := range sortedKeys(vars) {
+		if strings.Contains(vars[k], delimiter) {
+			return "", fmt.Errorf("value of '%s' contains heredoc delimiter '%s'", k, delimiter)
+		}
+		fmt.Fprintf(&out, "%s<<%s\n%s\n%s\n", k, delimiter, vars[k], delimiter)
+	}
+	return out.String(), nil
+}
+
+// GitLab dotenv report doesn't support quoting and multiline values, everything after '=' is the value
+func formatGitlabDotEnvReport(c *DotEnvCommand, vars map[string]string) (string, error) {
+	var out strings.Builder
+	for _, k := range sortedKeys(vars) {
+		if !gitlabVarName.MatchString(k) {
+			return "", fmt.Errorf("name of '%s' is not allowed in GitLab dotenv report, only letters, digits and underscores are", k)
+		}
+		if strings.ContainsAny(vars[k], "\r\n") {
+			return "", fmt.Errorf("value of '%s' contains line breaks and cant be written to GitLab dotenv report", k)
+		}
+		fmt.Fprintf(&out, "%s=%s\n", k, vars[k])
+	}
+	return out.String(), nil
+}
+
+// formatAzureVariables prints 'task.setvariable' logging commands, every var resolved from a provider is set as secret one,
+// so its value is never echoed into pipeline logs, even a plain SSM String parameter. Values embedding secrets are secret too.
+// Secret variables are not mapped into env of the next steps by Azure DevOps, so only plain vars of the source are regular ones
+func formatAzureVariables(c *DotEnvCommand, vars map[string]string) (string, error) {
+	var out strings.Builder
+	for _, k := range sortedKeys(vars) {
+		isSecret := c.isResolvedVar(k) || c.log.IsSecret(vars[k])
+		fmt.Fprintf(&out, "##vso[task.setvariable variable=%s;issecret=%t]%s\n",
+			azurePropertyEscaper.Replace(k), isSecret, azureDataEscaper.Replace(vars[k]))
+	}
+	return out.String(), nil
+}
+
+// isResolvedVar is true if the value of the var is resolved from a provider or loaded by path
+func (c *DotEnvCommand) isResolvedVar(k string) bool {
+	if _, ok := c.pathValues[k]; ok {
+		return true
+	}
+	for _, r := range c.resolved {
+		if r.envVar == k && r.isFound {
+			return true
+		}
+	}
+	return false
+}
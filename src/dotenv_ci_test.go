@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFormatGithubEnvFile(t *testing.T) {
+	c := testDotEnvCommand(t)
+	vars := map[string]string{"B": "line 1\nline 2\n", "A": "x=y"}
+
+	got, err := formatGithubEnvFile(c, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := regexp.MustCompile(`^A<<(ghadelimiter_[0-9a-f]{32})\n`).FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("formatGithubEnvFile() = %q, heredoc delimiter is expected", got)
+	}
+	d := m[1]
+
+	want := "A<<" + d + "\nx=y\n" + d + "\nB<<" + d + "\nline 1\nline 2\n\n" + d + "\n"
+	if got != want {
+		t.Errorf("formatGithubEnvFile() = %q, want %q", got, want)
+	}
+
+	// every run has a new delimiter, so it cant be guessed by the value
+	again, err := formatGithubEnvFile(c, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(again, d) {
+		t.Errorf("formatGithubEnvFile() reuses delimiter %s", d)
+	}
+}
+
+func TestFormatGitlabDotEnvReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", vars: map[string]string{"B": "x y", "A_1": `"quoted" $x`}, want: "A_1=\"quoted\" $x\nB=x y\n"},
+		{name: "dotted name", vars: map[string]string{"A.B": "1"}, wantErr: true},
+		{name: "dashed name", vars: map[string]string{"A-B": "1"}, wantErr: true},
+		{name: "newline", vars: map[string]string{"A": "line 1\nline 2"}, wantErr: true},
+		{name: "carriage return", vars: map[string]string{"A": "line 1\r"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatGitlabDotEnvReport(testDotEnvCommand(t), tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatGitlabDotEnvReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("formatGitlabDotEnvReport() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAzureVariables(t *testing.T) {
+	c := testDotEnvCommand(t)
+	c.log.Mask("s3cr3t")
+	c.resolved = []resolvedVar{
+		{envVar: "DB_HOST", scheme: ssmScheme, ref: "/app/db/host", value: "db1", isFound: true,
+			metadata: &SecretMetadata{Type: "String"}},
+		{envVar: "DB_USER", scheme: envScheme, ref: "DB_USER", value: "app", isFound: true},
+		{envVar: "API_KEY", scheme: ssmScheme, ref: "/app/api/key"},
+	}
+	c.pathValues = map[string]string{"CACHE_HOST": "cache1"}
+
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{"plain", "A", "1", "##vso[task.setvariable variable=A;issecret=false]1\n"},
+		{"secret", "DB_PASSWORD", "s3cr3t", "##vso[task.setvariable variable=DB_PASSWORD;issecret=true]s3cr3t\n"},
+		{"embedded secret", "DSN", "app:s3cr3t@db1", "##vso[task.setvariable variable=DSN;issecret=true]app:s3cr3t@db1\n"},
+		{"plain SSM String parameter", "DB_HOST", "db1", "##vso[task.setvariable variable=DB_HOST;issecret=true]db1\n"},
+		{"value of other provider", "DB_USER", "app", "##vso[task.setvariable variable=DB_USER;issecret=true]app\n"},
+		{"value loaded by path", "CACHE_HOST", "cache1", "##vso[task.setvariable variable=CACHE_HOST;issecret=true]cache1\n"},
+		{"missing value", "API_KEY", valueNotExists, "##vso[task.setvariable variable=API_KEY;issecret=false]" + valueNotExists + "\n"},
+		{"percent", "A", "100%", "##vso[task.setvariable variable=A;issecret=false]100%AZP25\n"},
+		{"newlines", "A", "line 1\r\nline 2", "##vso[task.setvariable variable=A;issecret=false]line 1%0D%0Aline 2\n"},
+		// properties end with ';' and ']', those are kept in the value
+		{"property separators", "A;issecret=false]", "a;b]c", "##vso[task.setvariable variable=A%3Bissecret=false%5D;issecret=false]a;b]c\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatAzureVariables(c, map[string]string{tt.key: tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("formatAzureVariables() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// In exec mode every positional argument after the environment belongs to the command,
+// so dotEnvFile argument is a command name there
+func (c *DotEnvCommand) validateExec() {
+	if c.dotEnvFileOut != "" {
+		c.command = append([]string{c.dotEnvFileOut}, c.command...)
+		c.dotEnvFileOut = ""
+	}
+
+	if len(c.command) == 0 {
+		c.log.ErrorFWithUsage("Command must be provided in exec mode")
+	}
+
+	if c.exportVars {
+		c.log.ErrorFWithUsage("Flags --exec and --export cant be used together")
+	}
+}
+
+// exec replaces tfconfig process by the command with resolved vars like ssm-env does,
+// so signals of the terminal and the exit code belong to the command itself
+func (c *DotEnvCommand) exec() {
+	path, err := exec.LookPath(c.command[0])
+	c.log.must(err)
+
+	c.log.Debug("Exec: %s", path)
+	c.log.must(syscall.Exec(path, c.command, mergeEnviron(os.Environ(), c.dotEnvMap)))
+}
+
+// mergeEnviron overrides environ entries in 'KEY=value' form by vars
+func mergeEnviron(environ []string, vars map[string]string) []string {
+	merged := make([]string, 0, len(environ)+len(vars))
+	for _, e := range environ {
+		name := e
+		if i := strings.IndexByte(e, '='); i >= 0 {
+			name = e[:i]
+		}
+		if _, ok := vars[name]; !ok {
+			merged = append(merged, e)
+		}
+	}
+
+	for _, k := range sortedKeys(vars) {
+		merged = append(merged, k+"="+vars[k])
+	}
+	return merged
+}
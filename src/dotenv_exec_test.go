@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeEnviron(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		vars    map[string]string
+		want    []string
+	}{
+		{
+			name:    "vars are appended sorted",
+			environ: []string{"PATH=/bin"},
+			vars:    map[string]string{"B": "2", "A": "1"},
+			want:    []string{"PATH=/bin", "A=1", "B=2"},
+		},
+		{
+			name:    "vars override environ",
+			environ: []string{"HOME=/root", "DB_HOST=localhost", "TERM=xterm"},
+			vars:    map[string]string{"DB_HOST": "db1"},
+			want:    []string{"HOME=/root", "TERM=xterm", "DB_HOST=db1"},
+		},
+		{
+			name:    "empty values override too",
+			environ: []string{"DB_HOST=localhost"},
+			vars:    map[string]string{"DB_HOST": ""},
+			want:    []string{"DB_HOST="},
+		},
+		{
+			name:    "values containing '=' are kept",
+			environ: []string{"OPTS=a=b"},
+			vars:    map[string]string{"URL": "pg://h/db?sslmode=require"},
+			want:    []string{"OPTS=a=b", "URL=pg://h/db?sslmode=require"},
+		},
+		{
+			name:    "entries without '=' are matched by the whole entry",
+			environ: []string{"BROKEN", "KEPT"},
+			vars:    map[string]string{"BROKEN": "fixed"},
+			want:    []string{"KEPT", "BROKEN=fixed"},
+		},
+		{
+			name:    "names are case sensitive",
+			environ: []string{"path=/usr/bin"},
+			vars:    map[string]string{"PATH": "/bin"},
+			want:    []string{"path=/usr/bin", "PATH=/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeEnviron(tt.environ, tt.vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeEnviron() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExec runs exec in a child test process, since the process is replaced by the command
+func TestExec(t *testing.T) {
+	if command := os.Getenv("TFCONFIG_TEST_EXEC"); command != "" {
+		c := testDotEnvCommand(t)
+		c.command = strings.Split(command, "|")
+		c.dotEnvMap = map[string]string{"DB_HOST": "db1"}
+		c.exec()
+		return
+	}
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tests := []struct {
+		name     string
+		command  []string
+		want     string
+		code     int
+		requires string
+	}{
+		{"exit code", []string{"sh", "-c", "printf %s \"$DB_HOST\"; exit 3"}, "db1", 3, ""},
+		{"terminated by signal", []string{"sh", "-c", "kill -TERM $$"}, "", -1, ""},
+		{"argv[0] is the command name", []string{"cat", "/proc/self/cmdline"}, "cat\x00/proc/self/cmdline\x00", 0, "/proc/self/cmdline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := os.Stat(tt.requires); tt.requires != "" && err != nil {
+				t.Skipf("%s is not available", tt.requires)
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestExec$")
+			cmd.Env = append(os.Environ(), "TFCONFIG_TEST_EXEC="+strings.Join(tt.command, "|"))
+			out, err := cmd.Output()
+
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != tt.want || code != tt.code {
+				t.Errorf("exec() = %q, exit code %d, want %q, exit code %d", out, code, tt.want, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dotEnvFormatter renders resolved vars in a particular output format
+type dotEnvFormatter func(c *DotEnvCommand, vars map[string]string) (string, error)
+
+// dotEnvFormatters is the registry of output formats available via --format flag
+var dotEnvFormatters = map[string]dotEnvFormatter{
+	"dotenv":           formatDotEnv,
+	"json":             formatJson,
+	"yaml":             formatYaml,
+	"docker":           formatDockerEnvFile,
+	"k8s-secret":       formatKubernetesSecret,
+	formatGithubEnv:    formatGithubEnvFile,
+	formatGitlabDotEnv: formatGitlabDotEnvReport,
+	formatAzure:        formatAzureVariables,
+}
+
+var dotEnvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+
+// kubernetesName is DNS-1123 subdomain which names of Kubernetes objects must be
+var kubernetesName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+const kubernetesNameMaxLen = 253
+
+// Scalars that can be written to YAML without quoting, YAML 1.1 booleans and nulls are not
+var yamlPlainScalar = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+var yamlReservedScalar = regexp.MustCompile(`^(?i:y|n|yes|no|on|off|true|false|null)$`)
+
+func dotEnvFormatNames() []string {
+	names := make([]string, 0, len(dotEnvFormatters))
+	for k := range dotEnvFormatters {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatDotEnv keeps order of vars, comments and blank lines of the source dotEnv file,
+// vars not defined there like ones loaded by path are appended sorted. generatedHeader is written first,
+// so the file is not merged as a layer
+func formatDotEnv(c *DotEnvCommand, vars map[string]string) (string, error) {
+	var out strings.Builder
+	out.WriteString(generatedHeader + "\n")
+	written := make(map[string]bool)
+
+	for _, l := range c.dotEnvLines {
+		if !l.isVar() {
+			if strings.TrimSpace(l.raw) == generatedHeader {
+				continue
+			}
+			out.WriteString(l.raw + "\n")
+			continue
+		}
+
+		value, ok := vars[l.key]
+		if !ok || written[l.key] {
+			continue
+		}
+
+		line := l.key + "=" + dotEnvQuote(value)
+		if l.export {
+			line = "export " + line
+		}
+		if l.comment != "" {
+			line += " " + l.comment
+		}
+
+		out.WriteString(line + "\n")
+		written[l.key] = true
+	}
+
+	for _, k := range sortedKeys(vars) {
+		if written[k] {
+			continue
+		}
+
+		out.WriteString(k + "=" + dotEnvQuote(vars[k]) + "\n")
+	}
+
+	return out.String(), nil
+}
+
+// dotEnvQuote double-quotes value so it's parsed back as is, '$' is escaped to not be interpolated
+func dotEnvQuote(value string) string {
+	return `"` + dotEnvEscaper.Replace(value) + `"`
+}
+
+func formatJson(c *DotEnvCommand, vars map[string]string) (string, error) {
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(vars); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func formatYaml(c *DotEnvCommand, vars map[string]string) (string, error) {
+	var out strings.Builder
+	for _, k := range sortedKeys(vars) {
+		fmt.Fprintf(&out, "%s: %s\n", yamlScalar(k), yamlString(vars[k]))
+	}
+	return out.String(), nil
+}
+
+// Docker env-file doesn't support quoting, everything after '=' is the value
+func formatDockerEnvFile(c *DotEnvCommand, vars map[string]string) (string, error) {
+	var out strings.Builder
+	for _, k := range sortedKeys(vars) {
+		if strings.ContainsAny(vars[k], "\r\n") {
+			return "", fmt.Errorf("value of '%s' contains line breaks and cant be written to Docker env-file", k)
+		}
+		fmt.Fprintf(&out, "%s=%s\n", k, vars[k])
+	}
+	return out.String(), nil
+}
+
+// formatKubernetesSecret writes Secret manifest, its name must be DNS-1123 subdomain like 'dev-eu'
+func formatKubernetesSecret(c *DotEnvCommand, vars map[string]string) (string, error) {
+	if err := validateKubernetesName(c.secretName); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	out.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n")
+	fmt.Fprintf(&out, "  name: %s\n", yamlScalar(c.secretName))
+	out.WriteString("type: Opaque\ndata:\n")
+	for _, k := range sortedKeys(vars) {
+		fmt.Fprintf(&out, "  %s: %s\n", yamlScalar(k), base64.StdEncoding.EncodeToString([]byte(vars[k])))
+	}
+	return out.String(), nil
+}
+
+func validateKubernetesName(name string) error {
+	if len(name) > kubernetesNameMaxLen || !kubernetesName.MatchString(name) {
+		return fmt.Errorf("Kubernetes Secret name '%s' must consist of lower case alphanumeric characters, '-' or '.', "+
+			"start and end with an alphanumeric character and be at most %d characters, use --secret-name to set it", name, kubernetesNameMaxLen)
+	}
+	return nil
+}
+
+func yamlScalar(value string) string {
+	if yamlPlainScalar.MatchString(value) && !yamlReservedScalar.MatchString(value) {
+		return value
+	}
+	return yamlString(value)
+}
+
+// JSON string is a valid YAML double-quoted scalar
+func yamlString(value string) string {
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
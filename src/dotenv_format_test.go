@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatDotEnvKeepsSourceOrder(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), `# Database
+DB_PASSWORD=ssm:///prod/db/password # rotated monthly
+export DB_HOST=db
+
+# Cache
+CACHE_URL='redis://cache'
+`)
+
+	vars := map[string]string{
+		"DB_PASSWORD": "p@ss word",
+		"DB_HOST":     "db",
+		"CACHE_URL":   "redis://cache",
+		// vars not defined in the source, like loaded by path, are appended sorted
+		"Z_FROM_PATH": "z",
+		"A_FROM_PATH": "a",
+	}
+
+	got, err := formatDotEnv(c, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := generatedHeader + `
+# Database
+DB_PASSWORD="p@ss word" # rotated monthly
+export DB_HOST="db"
+
+# Cache
+CACHE_URL="redis://cache"
+A_FROM_PATH="a"
+Z_FROM_PATH="z"
+`
+	if got != want {
+		t.Errorf("formatDotEnv() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatDotEnvSkipsMissingAndDuplicates(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), "A=1\nMISSING=ssm:///missing\nA=2\n")
+
+	got, err := formatDotEnv(c, map[string]string{"A": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := generatedHeader + "\nA=\"2\"\n"; got != want {
+		t.Errorf("formatDotEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatDotEnvGeneratedSource(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), generatedHeader+"\nA=1\n")
+
+	got, err := formatDotEnv(c, map[string]string{"A": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := generatedHeader + "\nA=\"1\"\n"; got != want {
+		t.Errorf("formatDotEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatDotEnvRoundTrip(t *testing.T) {
+	vars := map[string]string{
+		"BANG":      "Pa!ss",
+		"BACKTICK":  "a`b",
+		"DOLLAR":    "$HOME and ${USER}",
+		"NEWLINE":   "first\nsecond",
+		"ZEROS":     "007",
+		"QUOTES":    `say "hi" \n`,
+		"SEPARATOR": "a # b",
+	}
+
+	c := testDotEnvCommand(t)
+	content, err := formatDotEnv(c, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := withSource(t, c, content).interpolatedValues(c.dotEnvLines)
+	if !reflect.DeepEqual(got, vars) {
+		t.Errorf("parsed formatDotEnv() = %q, want %q\n%s", got, vars, content)
+	}
+}
+
+func TestSortedFormats(t *testing.T) {
+	c := testDotEnvCommand(t)
+	c.secretName = "app"
+	vars := map[string]string{"B": "yes", "A": "1", "C": "x y"}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", "{\n  \"A\": \"1\",\n  \"B\": \"yes\",\n  \"C\": \"x y\"\n}\n"},
+		{"yaml", "A: \"1\"\nB: \"yes\"\nC: \"x y\"\n"},
+		{"docker", "A=1\nB=yes\nC=x y\n"},
+		{"k8s-secret", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ntype: Opaque\ndata:\n  A: MQ==\n  B: eWVz\n  C: eCB5\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := dotEnvFormatters[tt.format](c, vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s =\n%s\nwant\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubernetesSecretName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"dev", false},
+		{"dev-eu", false},
+		{"app.dev-eu.1", false},
+		{"dev_eu", true},
+		{"Dev", true},
+		{"-dev", true},
+		{"dev-", true},
+		{"dev..eu", true},
+		{"", true},
+		{strings.Repeat("a", 254), true},
+	}
+
+	for _, tt := range tests {
+		c := testDotEnvCommand(t)
+		c.secretName = tt.name
+		if _, err := formatKubernetesSecret(c, map[string]string{"A": "1"}); (err != nil) != tt.wantErr {
+			t.Errorf("formatKubernetesSecret() with name %q error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
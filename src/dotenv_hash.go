@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+const (
+	// HashKeyEnvVar is the key of value hashes shared by the team and CI
+	HashKeyEnvVar = "TFCONFIG_HASH_KEY"
+
+	hashPrefix   = "hmac-sha256:"
+	hashKeyIdLen = 8
+	hashValueLen = 16
+)
+
+var errHashKeyNotSet = errors.New(HashKeyEnvVar + " env var is not set, value hashes are compared across machines, so the key must be shared by the team and CI")
+
+// valueHasher hashes values which are shown or committed, like lock file and diff columns.
+// The key is never stored in the repo, so low-entropy secrets cant be guessed by their hashes
+type valueHasher struct {
+	key []byte
+	id  string
+}
+
+func newValueHasher() (*valueHasher, error) {
+	key, err := hashKey()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("key id"))
+
+	return &valueHasher{key: key, id: hex.EncodeToString(mac.Sum(nil))[:hashKeyIdLen]}, nil
+}
+
+// hashKey is required, a key generated per machine would make hashes of committed lock file
+// incomparable with ones computed in CI or by teammates
+func hashKey() ([]byte, error) {
+	key := os.Getenv(HashKeyEnvVar)
+	if key == "" {
+		return nil, errHashKeyNotSet
+	}
+	return []byte(key), nil
+}
+
+// hash is like 'hmac-sha256:<key id>:<hash>', key id tells whether hashes can be compared
+func (h *valueHasher) hash(value string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(value))
+	return hashPrefix + h.id + ":" + hex.EncodeToString(mac.Sum(nil))[:hashValueLen]
+}
+
+// isComparable is true if the hash is made by the same key
+func (h *valueHasher) isComparable(hash string) bool {
+	return strings.HasPrefix(hash, hashPrefix+h.id+":")
+}
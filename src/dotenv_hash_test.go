@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValueHasher(t *testing.T) {
+	t.Setenv(HashKeyEnvVar, "team key")
+	hasher, err := newValueHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := hasher.hash("s3cr3t")
+	if !strings.HasPrefix(hash, hashPrefix) || len(hash) != len(hashPrefix)+hashKeyIdLen+1+hashValueLen {
+		t.Errorf("hash() = %q", hash)
+	}
+	if hash != hasher.hash("s3cr3t") || hash == hasher.hash("other") {
+		t.Error("hash() is not deterministic by value")
+	}
+	if !hasher.isComparable(hash) {
+		t.Error("isComparable() of own hash = false")
+	}
+
+	t.Setenv(HashKeyEnvVar, "another key")
+	another, err := newValueHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if another.isComparable(hash) || another.hash("s3cr3t") == hash {
+		t.Error("hash of another key is comparable")
+	}
+}
+
+func TestHashKeyRequired(t *testing.T) {
+	t.Setenv(HashKeyEnvVar, "")
+
+	if _, err := newValueHasher(); err != errHashKeyNotSet {
+		t.Errorf("newValueHasher() error = %v, want %v", err, errHashKeyNotSet)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	interpolationPending = iota
+	interpolationInProgress
+	interpolationDone
+)
+
+// interpolator expands '$VAR', '${VAR}', '${VAR:-default}' and '${VAR-default}' by vars and process environment,
+// only upper-case names are expanded,
+// '${scheme://ref}' is expanded by the value of reference embedded into the var
+type interpolator struct {
+	log *Log
+	// vars are replaced by expanded values
+	vars map[string]string
+	// expand contains vars which values should be expanded
+	expand map[string]bool
+	// embedded values are keyed by embeddedRef
+	embedded map[string]string
+	state    map[string]int
+}
+
+func newInterpolator(log *Log, vars map[string]string, expand map[string]bool, embedded map[string]string) *interpolator {
+	return &interpolator{
+		log:      log,
+		vars:     vars,
+		expand:   expand,
+		embedded: embedded,
+		state:    make(map[string]int),
+	}
+}
+
+// embeddedRef names reference embedded into value of the var like 'DATABASE_URL${ssm:///prod/db/password}'
+func embeddedRef(envVar string, ref string) string {
+	return envVar + "${" + ref + "}"
+}
+
+// expandAll expands values of every var that should be expanded
+func (i *interpolator) expandAll() error {
+	for _, k := range sortedKeys(i.vars) {
+		if _, _, err := i.lookup(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *interpolator) lookup(name string) (string, bool, error) {
+	value, ok := i.vars[name]
+	if !ok {
+		value, ok = os.LookupEnv(name)
+		return value, ok, nil
+	}
+
+	if !i.expand[name] || i.state[name] == interpolationDone {
+		return value, true, nil
+	}
+	if i.state[name] == interpolationInProgress {
+		return "", false, fmt.Errorf("variable '%s' refers to itself", name)
+	}
+
+	i.state[name] = interpolationInProgress
+	expanded, err := i.expandValue(name, value)
+	if err != nil {
+		return "", false, err
+	}
+	i.vars[name] = expanded
+	i.state[name] = interpolationDone
+
+	return expanded, true, nil
+}
+
+// expandValue expands value of envVar, '\$' is unescaped and expressions that cant be parsed are kept as is
+func (i *interpolator) expandValue(envVar string, value string) (string, error) {
+	var out strings.Builder
+
+	for pos := 0; pos < len(value); pos++ {
+		if strings.HasPrefix(value[pos:], `\$`) {
+			out.WriteByte('$')
+			pos++
+			continue
+		}
+		if value[pos] != '$' {
+			out.WriteByte(value[pos])
+			continue
+		}
+
+		if strings.HasPrefix(value[pos:], "${") {
+			end := closingBrace(value, pos+2)
+			if end < 0 {
+				out.WriteString(value[pos:])
+				break
+			}
+
+			expanded, err := i.expression(envVar, value[pos+2:end])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(expanded)
+			pos = end
+			continue
+		}
+
+		name := varName(value[pos+1:])
+		if name == "" {
+			out.WriteByte('$')
+			continue
+		}
+
+		expanded, err := i.variable(envVar, name)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(expanded)
+		pos += len(name)
+	}
+
+	return out.String(), nil
+}
+
+func (i *interpolator) expression(envVar string, expr string) (string, error) {
+	if value, ok := i.embedded[embeddedRef(envVar, expr)]; ok {
+		return value, nil
+	}
+
+	name := varName(expr)
+	operator := expr[len(name):]
+	if name == "" {
+		return "${" + expr + "}", nil
+	}
+
+	switch {
+	case operator == "":
+		return i.variable(envVar, name)
+	case strings.HasPrefix(operator, ":-"):
+		value, ok, err := i.lookup(name)
+		if err != nil || (ok && value != "") {
+			return value, err
+		}
+		return i.expandValue(envVar, operator[2:])
+	case strings.HasPrefix(operator, "-"):
+		value, ok, err := i.lookup(name)
+		if err != nil || ok {
+			return value, err
+		}
+		return i.expandValue(envVar, operator[1:])
+	}
+
+	return "${" + expr + "}", nil
+}
+
+// variable is expanded to empty string if it's not set
+func (i *interpolator) variable(envVar string, name string) (string, error) {
+	value, ok, err := i.lookup(name)
+	if err == nil && !ok {
+		i.log.Warning("Variable %s is not set, it's replaced by empty string in %s", name, envVar)
+	}
+	return value, err
+}
+
+// interpolations returns '${...}' expressions of the value
+func interpolations(value string) []string {
+	var expressions []string
+	for pos := 0; pos < len(value); pos++ {
+		if strings.HasPrefix(value[pos:], `\$`) {
+			pos++
+			continue
+		}
+		if !strings.HasPrefix(value[pos:], "${") {
+			continue
+		}
+
+		end := closingBrace(value, pos+2)
+		if end < 0 {
+			break
+		}
+		expressions = append(expressions, value[pos+2:end])
+		pos = end
+	}
+	return expressions
+}
+
+// closingBrace finds '}' of expression started at pos, default values can contain nested expressions
+func closingBrace(value string, pos int) int {
+	depth := 0
+	for i := pos; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// varName takes upper-case name like godotenv did, so values like 'pa$$word' and '$lowercase' are kept as is
+func varName(value string) string {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '_' || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return value[:i]
+	}
+	return value
+}
+
+// embeddedReferences finds references embedded into values like 'postgres://app:${ssm:///prod/db/password}@host/db'
+func (c *DotEnvCommand) embeddedReferences() map[string]string {
+	refs := make(map[string]string)
+	for k, v := range c.dotEnvMap {
+		if !c.interpolated[k] {
+			continue
+		}
+		for _, expr := range interpolations(v) {
+			if _, _, _, isFound := c.reference(embeddedRef(k, expr), expr); isFound {
+				refs[embeddedRef(k, expr)] = expr
+			}
+		}
+	}
+	return refs
+}
+
+// expandReferences expands vars in references like 'ssm:///${STAGE}/db/password' by plain vars and process environment
+func (c *DotEnvCommand) expandReferences() error {
+	plain := make(map[string]string)
+	for k, v := range c.pathValues {
+		plain[k] = v
+	}
+	var refs []string
+	for _, k := range sortedKeys(c.dotEnvMap) {
+		if c.isReference(k, c.dotEnvMap[k]) {
+			refs = append(refs, k)
+		} else {
+			plain[k] = c.dotEnvMap[k]
+		}
+	}
+
+	expander := newInterpolator(c.log, plain, c.interpolated, nil)
+	for _, k := range refs {
+		if !c.interpolated[k] || !strings.Contains(c.dotEnvMap[k], "$") {
+			continue
+		}
+		expanded, err := expander.expandValue(k, c.dotEnvMap[k])
+		if err != nil {
+			return err
+		}
+		c.dotEnvMap[k] = expanded
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInterpolatorExpandAll(t *testing.T) {
+	t.Setenv("TFCONFIG_TEST_HOME", "/home/app")
+
+	tests := []struct {
+		name   string
+		source string
+		want   map[string]string
+	}{
+		{
+			name:   "braces and bare names",
+			source: "HOST=db\nPORT=5432\nURL=postgres://${HOST}:$PORT/app",
+			want:   map[string]string{"URL": "postgres://db:5432/app"},
+		},
+		{
+			name:   "vars defined later",
+			source: "URL=http://${HOST}\nHOST=example.com",
+			want:   map[string]string{"URL": "http://example.com"},
+		},
+		{
+			name:   "chained",
+			source: "A=${B}/a\nB=${C}/b\nC=c",
+			want:   map[string]string{"A": "c/b/a", "B": "c/b"},
+		},
+		{
+			name:   "process environment",
+			source: "CONFIG=${TFCONFIG_TEST_HOME}/.config",
+			want:   map[string]string{"CONFIG": "/home/app/.config"},
+		},
+		{
+			name:   "defaults",
+			source: "EMPTY=\nA=${EMPTY:-a}\nB=${EMPTY-b}\nC=${TFCONFIG_TEST_UNSET-c}\nD=${TFCONFIG_TEST_UNSET:-${A}d}",
+			want:   map[string]string{"A": "a", "B": "", "C": "c", "D": "ad"},
+		},
+		{
+			name:   "unset is empty",
+			source: "A=x${TFCONFIG_TEST_UNSET}y",
+			want:   map[string]string{"A": "xy"},
+		},
+		{
+			name:   "escaped dollar",
+			source: `A="\${B} \$C"` + "\nB=b",
+			want:   map[string]string{"A": "${B} $C"},
+		},
+		{
+			name:   "single-quoted is not expanded",
+			source: "A='${B}'\nB=b",
+			want:   map[string]string{"A": "${B}"},
+		},
+		{
+			name:   "double dollar and lower-case names are kept",
+			source: "PASSWORD=pa$$word\nQUOTED=\"pa$$word\"\nLOWER=$lowercase ${lower} ${lower:-x}\nlowercase=x",
+			want:   map[string]string{"PASSWORD": "pa$$word", "QUOTED": "pa$$word", "LOWER": "$lowercase ${lower} ${lower:-x}"},
+		},
+		{
+			name:   "upper-case name ends at lower-case character",
+			source: "P=x\nA=$Path",
+			want:   map[string]string{"A": "xath"},
+		},
+		{
+			name:   "not expressions are kept",
+			source: "A=$ 1 ${ ${-x} ${B",
+			want:   map[string]string{"A": "$ 1 ${ ${-x} ${B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := parseDotEnv(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			vars := dotEnvValues(lines)
+			i := newInterpolator(&Log{ioWriter: io.Discard}, vars, dotEnvInterpolated(lines), nil)
+			if err := i.expandAll(); err != nil {
+				t.Fatalf("expandAll() error = %v", err)
+			}
+			for k, v := range tt.want {
+				if vars[k] != v {
+					t.Errorf("%s = %q, want %q", k, vars[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpolatorCycle(t *testing.T) {
+	for _, source := range []string{"A=${A}", "A=${B}\nB=$A", "A=${B:-x}\nB=${A}"} {
+		lines, err := parseDotEnv(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		i := newInterpolator(&Log{ioWriter: io.Discard}, dotEnvValues(lines), dotEnvInterpolated(lines), nil)
+		if err := i.expandAll(); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+			t.Errorf("%q: expandAll() error = %v, want cycle error", source, err)
+		}
+	}
+}
+
+func TestInterpolatorEmbedded(t *testing.T) {
+	vars := map[string]string{
+		"DATABASE_URL": "postgres://app:${ssm:///prod/db/password}@${HOST}/db",
+		"HOST":         "db",
+	}
+	embedded := map[string]string{
+		embeddedRef("DATABASE_URL", "ssm:///prod/db/password"): "p@ss",
+	}
+
+	i := newInterpolator(&Log{ioWriter: io.Discard}, vars, map[string]bool{"DATABASE_URL": true}, embedded)
+	if err := i.expandAll(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "postgres://app:p@ss@db/db"; vars["DATABASE_URL"] != want {
+		t.Errorf("DATABASE_URL = %q, want %q", vars["DATABASE_URL"], want)
+	}
+}
+
+func TestInterpolations(t *testing.T) {
+	got := interpolations(`a ${ssm:///x} \${B} ${C:-${D}} $E ${F`)
+	want := []string{"ssm:///x", "C:-${D}"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("interpolations() = %q, want %q", got, want)
+	}
+}
+
+func TestEmbeddedReferencesAndExpandReferences(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), strings.Join([]string{
+		"STAGE=prod",
+		"DB_PASSWORD=ssm:///${STAGE}/db/password",
+		"DATABASE_URL=postgres://app:${ssm:///prod/db/password}@db/app",
+		"LITERAL='${ssm:///prod/db/password}'",
+	}, "\n"))
+	c.ssm = &fakeSsm{}
+	c.registerProviders()
+
+	refs := c.embeddedReferences()
+	if len(refs) != 1 || refs[embeddedRef("DATABASE_URL", "ssm:///prod/db/password")] != "ssm:///prod/db/password" {
+		t.Errorf("embeddedReferences() = %v", refs)
+	}
+
+	if err := c.expandReferences(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ssm:///prod/db/password"; c.dotEnvMap["DB_PASSWORD"] != want {
+		t.Errorf("DB_PASSWORD = %q, want %q", c.dotEnvMap["DB_PASSWORD"], want)
+	}
+}
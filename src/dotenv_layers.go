@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"os"
+	"sort"
+	"strings"
+)
+
+// localLayerSuffix marks the layer of local overrides like '.env.dev.local', it's usually ignored by git
+const localLayerSuffix = ".local"
+
+// generatedHeader is the first line of dotEnv files written by the command, those are never merged as layers,
+// so resolved values of the previous run dont override references
+const generatedHeader = "# Generated by tfconfig dotenv, changes will be overwritten"
+
+func (c *DotEnvCommand) configureLayerFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("base-layer", "Merge shared .env file under .env.<environment> as the base layer, default: true. use --no-base-layer to disable it").
+		Default("true").
+		BoolVar(&c.baseLayer)
+
+	cmd.Flag("layer", "Extra dotEnv file merged over .env, .env.<environment> and .env.<environment>.local, can be repeated").
+		PlaceHolder("PATH").
+		StringsVar(&c.layers)
+}
+
+// sourceLayers lists dotEnv files in merge order: .env, .env.<environment>, .env.<environment>.local and --layer files,
+// vars of later layers override earlier ones. Optional .env and .local layers are skipped if those don't exist
+// or are written by the command, like 'tfconfig dotenv dev .env', files generated by the command are skipped as well
+func (c *DotEnvCommand) sourceLayers(outputs ...string) []string {
+	isOutput := func(layer string) bool {
+		for _, output := range outputs {
+			if output != "" && strings.EqualFold(layer, output) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var layers []string
+	add := func(layer string) {
+		if isGeneratedDotEnv(c.resolvePath(layer)) {
+			c.log.Warning("dotEnv layer '%s' is skipped, it's generated by tfconfig", layer)
+			return
+		}
+		layers = append(layers, layer)
+	}
+	require := func(layer string) {
+		if isExists, _ := ValidateFile(c.resolvePath(layer)); !isExists {
+			c.log.ErrorFWithUsage("dotEnv layer: '%s' does'nt exists", layer)
+		}
+		if isOutput(layer) {
+			c.log.ErrorF("dotEnv layer '%s' and destination dotEnv file must be different", layer)
+		}
+		add(layer)
+	}
+	optional := func(layer string) {
+		if isExists, _ := ValidateFile(c.resolvePath(layer)); !isExists {
+			return
+		}
+		if isOutput(layer) {
+			c.log.Debug("dotEnv layer '%s' is skipped, it's the destination file", layer)
+			return
+		}
+		add(layer)
+	}
+
+	if c.baseLayer {
+		base := strings.TrimSuffix(c.dotEnvFilePrefix, ".")
+		optional(base)
+		if len(layers) > 0 {
+			c.warnResolvedBaseLayer(base)
+		}
+	}
+	layers = append(layers, c.dotEnvFileSource)
+	optional(c.dotEnvFileSource + localLayerSuffix)
+
+	for _, layer := range c.layers {
+		require(layer)
+	}
+
+	c.log.ShowOpts("dotEnv layers", strings.Join(layers, ", "))
+
+	return layers
+}
+
+// warnResolvedBaseLayer warns if the base layer defines values of vars which are references in the source,
+// like .env written by tfconfig before generatedHeader was added, those values look resolved already
+func (c *DotEnvCommand) warnResolvedBaseLayer(base string) {
+	baseLines, err := readDotEnvLines(c.resolvePath(base))
+	if err != nil {
+		return
+	}
+	sourceLines, err := readDotEnvLines(c.resolvePath(c.dotEnvFileSource))
+	if err != nil {
+		return
+	}
+
+	baseValues := dotEnvValues(baseLines)
+	var resolved []string
+	for k, v := range dotEnvValues(sourceLines) {
+		if baseValue, ok := baseValues[k]; ok && strings.Contains(v, schemeSeparator) && !strings.Contains(baseValue, schemeSeparator) {
+			resolved = append(resolved, k)
+		}
+	}
+	if len(resolved) == 0 {
+		return
+	}
+
+	sort.Strings(resolved)
+	c.log.Warning("dotEnv layer '%s' defines values of references of '%s': %s, it looks like an output of older tfconfig, use --no-base-layer to skip it",
+		base, c.dotEnvFileSource, strings.Join(resolved, ", "))
+}
+
+// isGeneratedDotEnv checks the first line of the file for generatedHeader
+func isGeneratedDotEnv(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	return strings.TrimSpace(line) == generatedHeader
+}
+
+// mergeLayers reads lines of every layer, overridden vars keep position of the first layer which defines those,
+// new vars are appended with comments preceding them
+func (c *DotEnvCommand) mergeLayers() []dotEnvLine {
+	var merged []dotEnvLine
+	positions := make(map[string]int)
+	origins := make(map[string]string)
+
+	for i, layer := range c.layerFiles {
+		lines, err := readDotEnvLines(c.resolvePath(layer))
+		c.log.must(err)
+
+		var pending []dotEnvLine
+		for _, l := range lines {
+			if !l.isVar() {
+				pending = append(pending, l)
+				continue
+			}
+
+			position, isDefined := positions[l.key]
+			switch {
+			case strings.HasPrefix(l.value, ssmPathPrefix):
+				// path lines share the name like '_', so every one of them is kept
+				merged = append(append(merged, pending...), l)
+			case isDefined:
+				if origins[l.key] != layer {
+					c.log.Debug("Var: %s of %s is overridden by %s", l.key, origins[l.key], layer)
+				}
+				merged[position] = l
+				origins[l.key] = layer
+			default:
+				merged = append(merged, pending...)
+				positions[l.key] = len(merged)
+				merged = append(merged, l)
+				origins[l.key] = layer
+			}
+			pending = nil
+		}
+
+		// trailing comments are kept for the first layer only, so a single source file is written back as is
+		if i == 0 {
+			merged = append(merged, pending...)
+		}
+	}
+
+	for _, k := range sortedKeys(origins) {
+		c.log.Debug("Var: %s, layer: %s", k, origins[k])
+	}
+
+	return merged
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSourceLayersAndMergeOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		noBaseLayer bool
+		layers      []string
+		output      string
+		want        []string
+		source      string
+	}{
+		{
+			name:   "source only",
+			files:  map[string]string{".env.dev": "# db\nDB_HOST=db\n"},
+			want:   []string{".env.dev"},
+			source: "# db\nDB_HOST=db\n",
+		},
+		{
+			name: "base layer is disabled",
+			files: map[string]string{
+				".env":     "SHARED=base\n",
+				".env.dev": "DB_HOST=db\n",
+			},
+			noBaseLayer: true,
+			want:        []string{".env.dev"},
+			source:      "DB_HOST=db\n",
+		},
+		{
+			name: "base layer is skipped if it's the destination",
+			files: map[string]string{
+				".env":     "SHARED=base\n",
+				".env.dev": "DB_HOST=db\n",
+			},
+			output: ".env",
+			want:   []string{".env.dev"},
+			source: "DB_HOST=db\n",
+		},
+		{
+			name: "source overrides base layer in place",
+			files: map[string]string{
+				".env":     "# shared\nSHARED=base\nDB_HOST=base\n",
+				".env.dev": "DB_HOST=dev\n# dev only\nDEV=1\n",
+			},
+			want:   []string{".env", ".env.dev"},
+			source: "# shared\nSHARED=base\nDB_HOST=dev\n# dev only\nDEV=1\n",
+		},
+		{
+			name: "local and extra layers are merged in order",
+			files: map[string]string{
+				".env.dev":       "A=source\nB=source\nC=source\n",
+				".env.dev.local": "B=local\nC=local\nD=local\n",
+				".env.ci":        "C=ci\nE=ci\n",
+			},
+			layers: []string{".env.ci"},
+			want:   []string{".env.dev", ".env.dev.local", ".env.ci"},
+			source: "A=source\nB=local\nC=ci\nD=local\nE=ci\n",
+		},
+		{
+			name: "local layer is skipped if it's the destination",
+			files: map[string]string{
+				".env.dev":       "A=source\n",
+				".env.dev.local": "A=local\n",
+			},
+			output: ".env.dev.local",
+			want:   []string{".env.dev"},
+			source: "A=source\n",
+		},
+		{
+			name: "generated layers are skipped",
+			files: map[string]string{
+				".env.dev":       "A=ssm:///app/a\n",
+				".env.dev.local": generatedHeader + "\nA=resolved\n",
+				".env.ci":        generatedHeader + "\nA=resolved\n",
+			},
+			layers: []string{".env.ci"},
+			want:   []string{".env.dev"},
+			source: "A=ssm:///app/a\n",
+		},
+		{
+			name: "generated base layer is skipped",
+			files: map[string]string{
+				".env":     generatedHeader + "\nA=resolved\n",
+				".env.dev": "A=ssm:///app/a\n",
+			},
+			output: ".env.out",
+			want:   []string{".env.dev"},
+			source: "A=ssm:///app/a\n",
+		},
+		{
+			name: "path lines of every layer are kept",
+			files: map[string]string{
+				".env.dev":       "_=ssm-path:///app/common\nA=1\n",
+				".env.dev.local": "_=ssm-path:///app/local\n",
+			},
+			want:   []string{".env.dev", ".env.dev.local"},
+			source: "_=ssm-path:///app/common\nA=1\n_=ssm-path:///app/local\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testDotEnvCommand(t)
+			c.baseLayer = !tt.noBaseLayer
+			c.layers = tt.layers
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(c.app.projectPath, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c.layerFiles = c.sourceLayers(tt.output)
+			if !reflect.DeepEqual(c.layerFiles, tt.want) {
+				t.Fatalf("sourceLayers() = %v, want %v", c.layerFiles, tt.want)
+			}
+
+			var merged []string
+			for _, l := range c.mergeLayers() {
+				merged = append(merged, l.raw+"\n")
+			}
+			if got := strings.Join(merged, ""); got != tt.source {
+				t.Errorf("mergeLayers() =\n%s\nwant\n%s", got, tt.source)
+			}
+		})
+	}
+}
+
+func TestIsGeneratedDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]bool{
+		generatedHeader + "\nA=1\n": true,
+		generatedHeader:             true,
+		"A=1\n" + generatedHeader:   false,
+		"# Generated by hand\nA=1":  false,
+	}
+
+	i := 0
+	for content, want := range tests {
+		i++
+		path := filepath.Join(dir, ".env."+strings.Repeat("x", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := isGeneratedDotEnv(path); got != want {
+			t.Errorf("isGeneratedDotEnv(%q) = %t, want %t", content, got, want)
+		}
+	}
+
+	if isGeneratedDotEnv(filepath.Join(dir, "missing")) {
+		t.Error("isGeneratedDotEnv() of missing file = true")
+	}
+}
+
+func TestSourceLayersAbsolutePath(t *testing.T) {
+	c := testDotEnvCommand(t)
+	layer := filepath.Join(t.TempDir(), "abs.env")
+	for path, content := range map[string]string{
+		filepath.Join(c.app.projectPath, ".env.dev"): "A=source\nB=source\n",
+		layer: "B=abs\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.layers = []string{layer}
+
+	c.layerFiles = c.sourceLayers()
+	if want := []string{".env.dev", layer}; !reflect.DeepEqual(c.layerFiles, want) {
+		t.Fatalf("sourceLayers() = %v, want %v", c.layerFiles, want)
+	}
+	if got, want := dotEnvValues(c.mergeLayers()), map[string]string{"A": "source", "B": "abs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeLayers() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvedBaseLayerWarning(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		want string
+	}{
+		{"shared vars", "SHARED=1\nDB_HOST=ssm:///app/db/host\n", ""},
+		{"resolved references", "SHARED=1\nDB_HOST=db1\nDB_PASSWORD=s3cr3t\n", "DB_HOST, DB_PASSWORD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testDotEnvCommand(t)
+			c.baseLayer = true
+			output := new(bytes.Buffer)
+			c.log.ioWriter = output
+			for name, content := range map[string]string{
+				".env":     tt.base,
+				".env.dev": "DB_HOST=ssm:///app/db/host\nDB_PASSWORD=ssm:///app/db/password\nDB_NAME=app\n",
+			} {
+				if err := os.WriteFile(filepath.Join(c.app.projectPath, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c.sourceLayers()
+			warned := strings.Contains(output.String(), "[WARNING]")
+			if warned != (tt.want != "") || !strings.Contains(output.String(), tt.want) {
+				t.Errorf("sourceLayers() output = %q, want warning about %q", output.String(), tt.want)
+			}
+		})
+	}
+}
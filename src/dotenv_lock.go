@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultLockFileSuffix is appended to the source dotEnv file name, like '.env.production.lock'
+const defaultLockFileSuffix = ".lock"
+
+// lockEntry is Parameter Store parameter version resolved for env var, value itself is never written.
+// The lock file is committed, so the hash is keyed by the key that is never stored in the repo
+type lockEntry struct {
+	Parameter string `json:"parameter"`
+	Version   int64  `json:"version"`
+	Type      string `json:"type"`
+	Hash      string `json:"hash"`
+}
+
+// lockFile contains entries by env var name
+type lockFile map[string]lockEntry
+
+func (c *DotEnvCommand) lockFilePath() string {
+	return GetFullPath(c.app.projectPath, c.dotEnvFileSource+defaultLockFileSuffix)
+}
+
+func (c *DotEnvCommand) readLock() lockFile {
+	lock := lockFile{}
+
+	content, err := os.ReadFile(c.lockFilePath())
+	c.log.must(err)
+	c.log.must(json.Unmarshal(content, &lock))
+
+	return lock
+}
+
+// writeLock records resolved versions of SSM parameters
+func (c *DotEnvCommand) writeLock(resolved []resolvedVar) {
+	hasher, err := newValueHasher()
+	c.log.must(err)
+
+	lock := lockFile{}
+	for _, r := range resolved {
+		if r.scheme != ssmScheme || r.metadata == nil {
+			continue
+		}
+
+		name, _ := ssmSelector(r.ref)
+		lock[r.envVar] = lockEntry{
+			Parameter: name,
+			Version:   r.metadata.Version,
+			Type:      r.metadata.Type,
+			Hash:      hasher.hash(r.value),
+		}
+	}
+
+	content, err := json.MarshalIndent(lock, "", "  ")
+	c.log.must(err)
+
+	c.app.createOrPopulateFile(c.lockFilePath(), string(content)+"\n")
+	c.log.Info("Lock file '%s' has been written", c.dotEnvFileSource+defaultLockFileSuffix)
+}
+
+// lockedRef pins SSM parameter to the version from lock file, explicit selectors are kept
+func (c *DotEnvCommand) lockedRef(envVar string, ref string) string {
+	if !c.locked || c.lock == nil {
+		return ref
+	}
+
+	if _, selector := ssmSelector(ref); selector != "" {
+		return ref
+	}
+
+	if entry, ok := c.lock[envVar]; ok && entry.Parameter == ref {
+		c.log.Debug("Parameter: %s is locked to version: %d", ref, entry.Version)
+		return ssmVersionRef(ref, entry.Version)
+	}
+
+	return ref
+}
+
+// checkFrozen reports every SSM parameter which current version differs from lock file,
+// values are compared by hashes if those are made by the same key, like parameter recreated with the same version
+func (c *DotEnvCommand) checkFrozen(resolved []resolvedVar) error {
+	hasher, err := newValueHasher()
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	seen := make(map[string]bool)
+
+	for _, r := range resolved {
+		if r.scheme != ssmScheme {
+			continue
+		}
+		seen[r.envVar] = true
+
+		name, _ := ssmSelector(r.ref)
+		entry, ok := c.lock[r.envVar]
+		switch {
+		case !ok:
+			changed = append(changed, fmt.Sprintf("%s (%s not locked)", r.envVar, name))
+		case r.metadata == nil:
+			changed = append(changed, fmt.Sprintf("%s (%s not exists)", r.envVar, name))
+		case entry.Parameter != name:
+			changed = append(changed, fmt.Sprintf("%s (%s, locked %s)", r.envVar, name, entry.Parameter))
+		case entry.Version != r.metadata.Version:
+			changed = append(changed, fmt.Sprintf("%s (%s version %d, locked %d)", r.envVar, name, r.metadata.Version, entry.Version))
+		case hasher.isComparable(entry.Hash) && hasher.hash(r.value) != entry.Hash:
+			changed = append(changed, fmt.Sprintf("%s (%s value differs, version %d)", r.envVar, name, entry.Version))
+		}
+	}
+
+	for _, envVar := range sortedLockKeys(c.lock) {
+		if !seen[envVar] {
+			changed = append(changed, fmt.Sprintf("%s (%s removed)", envVar, c.lock[envVar].Parameter))
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("frozen mode, %d parameters differ from lock file: %s", len(changed), strings.Join(changed, ", "))
+	}
+
+	c.log.Info("Parameter Store versions match lock file '%s'", c.dotEnvFileSource+defaultLockFileSuffix)
+
+	return nil
+}
+
+func sortedLockKeys(lock lockFile) []string {
+	keys := make([]string, 0, len(lock))
+	for k := range lock {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
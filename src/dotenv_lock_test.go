@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteLockSkipsValues(t *testing.T) {
+	t.Setenv(HashKeyEnvVar, "test key")
+	c := testDotEnvCommand(t)
+
+	secret := "s3cr3t-value"
+	c.writeLock([]resolvedVar{
+		{envVar: "DB_PASSWORD", scheme: ssmScheme, ref: "/app/db/password", value: secret, isFound: true,
+			metadata: &SecretMetadata{Version: 7, Type: "SecureString"}},
+		{envVar: "API_KEY", scheme: ssmScheme, ref: "/app/api/key:3", value: "other", isFound: true,
+			metadata: &SecretMetadata{Version: 3, Type: "SecureString"}},
+		{envVar: "VAULT_TOKEN", scheme: vaultScheme, ref: "secret/app#token", value: "vault", isFound: true},
+	})
+
+	content, err := os.ReadFile(c.lockFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(secret))
+	for _, leaked := range []string{secret, hex.EncodeToString(sum[:])[:12]} {
+		if strings.Contains(string(content), leaked) {
+			t.Errorf("lock file contains %q:\n%s", leaked, content)
+		}
+	}
+
+	hasher, err := newValueHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := c.readLock()
+	want := lockFile{
+		"DB_PASSWORD": {Parameter: "/app/db/password", Version: 7, Type: "SecureString", Hash: hasher.hash(secret)},
+		"API_KEY":     {Parameter: "/app/api/key", Version: 3, Type: "SecureString", Hash: hasher.hash("other")},
+	}
+	if len(lock) != len(want) {
+		t.Fatalf("lock = %v, want %v", lock, want)
+	}
+	for k, entry := range want {
+		if lock[k] != entry {
+			t.Errorf("lock[%s] = %+v, want %+v", k, lock[k], entry)
+		}
+	}
+}
+
+func TestCheckFrozen(t *testing.T) {
+	t.Setenv(HashKeyEnvVar, "test key")
+	hasher, err := newValueHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := lockFile{
+		"DB_PASSWORD": {Parameter: "/app/db/password", Version: 7, Type: "SecureString", Hash: hasher.hash("v")},
+		// hashes made by another key are not compared
+		"API_KEY": {Parameter: "/app/api/key", Version: 1, Type: "SecureString", Hash: hashPrefix + "00000000:0123456789abcdef"},
+	}
+	resolve := func(version int64, value string) []resolvedVar {
+		return []resolvedVar{
+			{envVar: "DB_PASSWORD", scheme: ssmScheme, ref: "/app/db/password", value: value, isFound: true,
+				metadata: &SecretMetadata{Version: version, Type: "SecureString"}},
+			{envVar: "API_KEY", scheme: ssmScheme, ref: "/app/api/key", value: "key", isFound: true,
+				metadata: &SecretMetadata{Version: 1, Type: "SecureString"}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		resolved []resolvedVar
+		err      string
+	}{
+		{"same version", resolve(7, "v"), ""},
+		{"rotated", resolve(8, "w"), "DB_PASSWORD (/app/db/password version 8, locked 7)"},
+		{"recreated with the same version", resolve(7, "w"), "DB_PASSWORD (/app/db/password value differs, version 7)"},
+		{"removed", resolve(7, "v")[1:], "DB_PASSWORD (/app/db/password removed)"},
+		{"not locked", append(resolve(7, "v"), resolvedVar{envVar: "NEW_KEY", scheme: ssmScheme, ref: "/app/new/key",
+			metadata: &SecretMetadata{Version: 1}}), "NEW_KEY (/app/new/key not locked)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testDotEnvCommand(t)
+			c.lock = lock
+
+			err := c.checkFrozen(tt.resolved)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("checkFrozen() error = %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("checkFrozen() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
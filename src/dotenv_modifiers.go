@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Reference modifiers like 'ssm:///x?decode=base64&json=.db.host&file=/tmp/db.json' transform resolved values,
+// those are applied in order: decode, json, file
+const (
+	modifierDecode = "decode"
+	modifierJson   = "json"
+	modifierFile   = "file"
+
+	decodeBase64 = "base64"
+)
+
+type valueModifiers struct {
+	decode string
+	json   string
+	// file is the path which value is written to, the var is set to the path
+	file string
+}
+
+// splitModifiers strips modifiers from query of the reference, other query parameters
+// like vault '?version=N' are kept for the provider, the reference is returned as is if it has no modifiers
+func splitModifiers(ref string) (string, valueModifiers, error) {
+	var m valueModifiers
+
+	start := strings.Index(ref, "?")
+	if start < 0 {
+		return ref, m, nil
+	}
+
+	end := len(ref)
+	if i := strings.Index(ref[start:], "#"); i >= 0 {
+		end = start + i
+	}
+
+	var kept []string
+	isModified := false
+	for _, param := range strings.Split(ref[start+1:end], "&") {
+		key, value, _ := strings.Cut(param, "=")
+		switch key {
+		case modifierDecode:
+			if value != decodeBase64 {
+				return "", m, fmt.Errorf("reference '%s': unsupported decoding '%s', only '%s' is supported", ref, value, decodeBase64)
+			}
+			m.decode = value
+		case modifierJson:
+			if !strings.HasPrefix(value, ".") {
+				return "", m, fmt.Errorf("reference '%s': JSON path '%s' must start with '.'", ref, value)
+			}
+			m.json = value
+		case modifierFile:
+			if value == "" {
+				return "", m, fmt.Errorf("reference '%s': file path is empty", ref)
+			}
+			m.file = value
+		default:
+			kept = append(kept, param)
+			continue
+		}
+		isModified = true
+	}
+
+	if !isModified {
+		return ref, m, nil
+	}
+
+	stripped := ref[:start]
+	if len(kept) > 0 {
+		stripped += "?" + strings.Join(kept, "&")
+	}
+	return stripped + ref[end:], m, nil
+}
+
+// transform decodes value and picks JSON field, file is written separately
+func (m valueModifiers) transform(value string) (string, error) {
+	if m.decode == decodeBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("value cant be decoded from base64: %v", err)
+		}
+		value = string(decoded)
+	}
+
+	if m.json != "" {
+		var err error
+		if value, err = jsonPathValue(value, m.json); err != nil {
+			return "", err
+		}
+	}
+
+	return value, nil
+}
+
+// jsonPathValue picks field by path like '.db.host' or '.hosts.0', string fields are returned as is
+func jsonPathValue(value string, path string) (string, error) {
+	var field interface{}
+	if err := json.Unmarshal([]byte(value), &field); err != nil {
+		return "", fmt.Errorf("value is not a JSON, path '%s' cant be picked", path)
+	}
+
+	if path != "." {
+		for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+			switch node := field.(type) {
+			case map[string]interface{}:
+				next, ok := node[key]
+				if !ok {
+					return "", fmt.Errorf("JSON path '%s' not exists, key '%s' is missing", path, key)
+				}
+				field = next
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", fmt.Errorf("JSON path '%s' not exists, index '%s' is out of range", path, key)
+				}
+				field = node[i]
+			default:
+				return "", fmt.Errorf("JSON path '%s' not exists, '%s' is not an object or array", path, key)
+			}
+		}
+	}
+
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(field)
+	return string(b), err
+}
+
+// valueFilePath is the path of '?file=' modifier, relative paths are resolved from the project path
+func (c *DotEnvCommand) valueFilePath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return GetFullPath(c.app.projectPath, file)
+}
+
+// writeValueFiles writes values of '?file=' modifiers, it's called once strict and frozen checks are passed,
+// so nothing is written if resolving fails
+func (c *DotEnvCommand) writeValueFiles() error {
+	for _, r := range c.resolved {
+		if !r.isFound || r.file == "" {
+			continue
+		}
+		if err := c.writeValueFile(c.valueFilePath(r.file), r.value); err != nil {
+			return fmt.Errorf("%s (%s%s%s): %v", r.envVar, r.scheme, schemeSeparator, r.ref, err)
+		}
+	}
+	return nil
+}
+
+// writeValueFile writes value readable by the owner only, missing parent dirs are created
+func (c *DotEnvCommand) writeValueFile(path string, value string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// permissions of existing file are changed before the value is written
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		return err
+	}
+
+	c.log.Debug("Value has been written to %s", path)
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitModifiers(t *testing.T) {
+	tests := []struct {
+		ref       string
+		want      string
+		modifiers valueModifiers
+	}{
+		{"/app/db", "/app/db", valueModifiers{}},
+		{"/app/db?decode=base64", "/app/db", valueModifiers{decode: decodeBase64}},
+		{"/app/db?json=.db.host&file=db/host", "/app/db", valueModifiers{json: ".db.host", file: "db/host"}},
+		{"secret/app?version=2&json=.a#key", "secret/app?version=2#key", valueModifiers{json: ".a"}},
+		{"secret/app?version=2#key", "secret/app?version=2#key", valueModifiers{}},
+		{"app/db?version-stage=AWSPREVIOUS#password", "app/db?version-stage=AWSPREVIOUS#password", valueModifiers{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, modifiers, err := splitModifiers(tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || modifiers != tt.modifiers {
+				t.Errorf("splitModifiers() = %q, %+v, want %q, %+v", got, modifiers, tt.want, tt.modifiers)
+			}
+		})
+	}
+}
+
+func TestSplitModifiersErrors(t *testing.T) {
+	for _, ref := range []string{"/app/db?decode=hex", "/app/db?json=db.host", "/app/db?file="} {
+		if _, _, err := splitModifiers(ref); err == nil {
+			t.Errorf("splitModifiers(%q) error = nil", ref)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		modifiers valueModifiers
+		value     string
+		want      string
+		isError   bool
+	}{
+		{"decode", valueModifiers{decode: decodeBase64}, "aGVsbG8=\n", "hello", false},
+		{"decode then json", valueModifiers{decode: decodeBase64, json: ".a"}, "eyJhIjoiYiJ9", "b", false},
+		{"string field", valueModifiers{json: ".db.host"}, `{"db":{"host":"db1"}}`, "db1", false},
+		{"array item", valueModifiers{json: ".hosts.1"}, `{"hosts":["a","b"]}`, "b", false},
+		{"object field", valueModifiers{json: ".db"}, `{"db":{"port":5432}}`, `{"port":5432}`, false},
+		{"whole value", valueModifiers{json: "."}, `[1, 2]`, `[1,2]`, false},
+		{"missing key", valueModifiers{json: ".db.user"}, `{"db":{}}`, "", true},
+		{"out of range", valueModifiers{json: ".hosts.2"}, `{"hosts":["a"]}`, "", true},
+		{"not JSON", valueModifiers{json: ".a"}, "plain", "", true},
+		{"not base64", valueModifiers{decode: decodeBase64}, "!!!", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.modifiers.transform(tt.value)
+			if (err != nil) != tt.isError {
+				t.Fatalf("transform() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("transform() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceModifiersByProvider(t *testing.T) {
+	c := testDotEnvCommand(t)
+	c.ssm = &fakeSsm{}
+	c.enabledProviders = []string{vaultScheme, cmdScheme, fileScheme}
+	c.registerProviders()
+
+	tests := []struct {
+		value string
+		ref   string
+		json  string
+	}{
+		{"ssm:///app/db?json=.host", "/app/db", ".host"},
+		{"vault://secret/app?json=.host#db", "secret/app#db", ".host"},
+		{"cmd://curl -s https://example.com/token?json=.host", "curl -s https://example.com/token?json=.host", ""},
+		{"file://secrets/db?json=.host", "secrets/db?json=.host", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, ref, modifiers, isFound := c.reference("VAR", tt.value)
+			if !isFound {
+				t.Fatal("reference() is not found")
+			}
+			if ref != tt.ref || modifiers.json != tt.json {
+				t.Errorf("reference() = %q, %+v, want %q, json %q", ref, modifiers, tt.ref, tt.json)
+			}
+		})
+	}
+}
+
+func TestWriteValueFiles(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), "TLS_CERT=ssm:///app/tls/cert?file=certs/tls/cert.pem")
+	c.ssm = &fakeSsm{parameters: map[string]string{"/app/tls/cert": "cert"}}
+	c.registerProviders()
+
+	if err := c.processDotEnv(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(c.app.projectPath, "certs", "tls", "cert.pem")
+	if c.dotEnvMap["TLS_CERT"] != path {
+		t.Errorf("TLS_CERT = %q, want %q", c.dotEnvMap["TLS_CERT"], path)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file is written before checks, stat error = %v", err)
+	}
+
+	if err := c.writeValueFiles(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "cert" {
+		t.Errorf("file content = %q, want %q", content, "cert")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteValueFilesAbsolutePath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "key.pem")
+
+	c := withSource(t, testDotEnvCommand(t), "TLS_KEY=ssm:///app/tls/key?file="+path)
+	c.ssm = &fakeSsm{parameters: map[string]string{"/app/tls/key": "key"}}
+	c.registerProviders()
+
+	if err := c.processDotEnv(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.writeValueFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "key" {
+		t.Errorf("file content = %q, error = %v", content, err)
+	}
+}
+
+func TestStrictModeWritesNoValueFiles(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), "TLS_CERT=ssm:///app/tls/cert?file=cert.pem\nTLS_KEY=ssm:///app/tls/key")
+	c.ssm = &fakeSsm{parameters: map[string]string{"/app/tls/cert": "cert"}}
+	c.strict = true
+	c.registerProviders()
+
+	if err := c.processDotEnv(context.Background()); err == nil {
+		t.Fatal("processDotEnv() error = nil, want strict mode error")
+	}
+	if _, err := os.Stat(filepath.Join(c.app.projectPath, "cert.pem")); !os.IsNotExist(err) {
+		t.Errorf("file is written in failed strict mode, stat error = %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dotEnvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// dotEnvLine is either a var or a comment or a blank line of dotEnv file
+type dotEnvLine struct {
+	key   string
+	value string
+	// quote is the quote of value, single-quoted values are taken literally
+	quote byte
+	// export and comment are kept to write the var back like 'export KEY=value # comment'
+	export  bool
+	comment string
+	// raw is the source text of the line, quoted values can span several lines
+	raw string
+}
+
+func (l dotEnvLine) isVar() bool {
+	return l.key != ""
+}
+
+// interpolated vars expand '${VAR}' expressions
+func (l dotEnvLine) interpolated() bool {
+	return l.isVar() && l.quote != '\''
+}
+
+func readDotEnvLines(dotEnvFile string) ([]dotEnvLine, error) {
+	content, err := os.ReadFile(dotEnvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := parseDotEnv(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", dotEnvFile, err)
+	}
+
+	return lines, nil
+}
+
+// parseDotEnv parses KEY=value, KEY="value" and KEY='value' lines with optional 'export ' prefix,
+// escapes of double-quoted values are unescaped except '\$' which is kept for interpolation
+func parseDotEnv(content string) ([]dotEnvLine, error) {
+	var lines []dotEnvLine
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	for pos, number := 0, 1; pos < len(content); number++ {
+		end := lineEnd(content, pos)
+		line := content[pos:end]
+
+		body := strings.TrimSpace(line)
+		if body == "" || strings.HasPrefix(body, "#") {
+			lines = append(lines, dotEnvLine{raw: line})
+			pos = end + 1
+			continue
+		}
+
+		isExport := strings.HasPrefix(body, "export ")
+		body = strings.TrimPrefix(body, "export ")
+		separator := strings.IndexAny(body, "=:")
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: separator '=' is missing", number)
+		}
+
+		key := strings.TrimSpace(body[:separator])
+		if !dotEnvKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name '%s'", number, key)
+		}
+
+		value := strings.TrimLeft(body[separator+1:], " \t")
+		entry := dotEnvLine{key: key, export: isExport}
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			// quoted value can continue on the next lines, so it's looked up in the whole content
+			start := pos + strings.IndexByte(line, value[0])
+			closing := closingQuote(content, start+1, value[0])
+			if closing < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of '%s'", number, key)
+			}
+
+			end = lineEnd(content, closing)
+			rest := strings.TrimSpace(content[closing+1 : end])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value of '%s'", number, key)
+			}
+			entry.comment = rest
+
+			entry.quote = value[0]
+			entry.value = content[start+1 : closing]
+			if entry.quote == '"' {
+				entry.value = unescapeDoubleQuoted(entry.value)
+			}
+			number += strings.Count(content[start:closing], "\n")
+		} else {
+			value, entry.comment = splitInlineComment(value)
+			entry.value = strings.TrimSpace(value)
+		}
+
+		entry.raw = content[pos:end]
+		lines = append(lines, entry)
+		pos = end + 1
+	}
+
+	return lines, nil
+}
+
+func dotEnvValues(lines []dotEnvLine) map[string]string {
+	vars := make(map[string]string)
+	for _, l := range lines {
+		if l.isVar() {
+			vars[l.key] = l.value
+		}
+	}
+	return vars
+}
+
+// dotEnvLiteralValues are values which are never interpolated, only '\$' kept by the parser is unescaped
+func dotEnvLiteralValues(lines []dotEnvLine) map[string]string {
+	vars := make(map[string]string)
+	for _, l := range lines {
+		if !l.isVar() {
+			continue
+		}
+		vars[l.key] = l.value
+		if l.quote == '"' {
+			vars[l.key] = strings.ReplaceAll(l.value, `\$`, "$")
+		}
+	}
+	return vars
+}
+
+func dotEnvInterpolated(lines []dotEnvLine) map[string]bool {
+	interpolated := make(map[string]bool)
+	for _, l := range lines {
+		if l.isVar() {
+			interpolated[l.key] = l.interpolated()
+		}
+	}
+	return interpolated
+}
+
+func lineEnd(content string, pos int) int {
+	if i := strings.IndexByte(content[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(content)
+}
+
+func closingQuote(content string, pos int, quote byte) int {
+	for i := pos; i < len(content); i++ {
+		if content[i] == '\\' && quote == '"' {
+			i++
+			continue
+		}
+		if content[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitInlineComment splits unquoted value and its comment, '#' must be preceded by whitespace
+func splitInlineComment(value string) (string, string) {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i], value[i:]
+		}
+	}
+	return value, ""
+}
+
+func unescapeDoubleQuoted(value string) string {
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case '"', '\\':
+			out.WriteByte(value[i])
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
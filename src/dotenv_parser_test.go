@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []dotEnvLine
+	}{
+		{
+			name:    "plain",
+			content: "KEY=value",
+			want:    []dotEnvLine{{key: "KEY", value: "value", raw: "KEY=value"}},
+		},
+		{
+			name:    "spaces around value are trimmed",
+			content: "KEY =  value  ",
+			want:    []dotEnvLine{{key: "KEY", value: "value", raw: "KEY =  value  "}},
+		},
+		{
+			name:    "colon separator",
+			content: "KEY: value",
+			want:    []dotEnvLine{{key: "KEY", value: "value", raw: "KEY: value"}},
+		},
+		{
+			name:    "empty value",
+			content: "KEY=",
+			want:    []dotEnvLine{{key: "KEY", raw: "KEY="}},
+		},
+		{
+			name:    "export",
+			content: "export KEY=value",
+			want:    []dotEnvLine{{key: "KEY", value: "value", export: true, raw: "export KEY=value"}},
+		},
+		{
+			name:    "inline comment",
+			content: "KEY=value # comment",
+			want:    []dotEnvLine{{key: "KEY", value: "value", comment: "# comment", raw: "KEY=value # comment"}},
+		},
+		{
+			name:    "hash without preceding whitespace is the value",
+			content: "KEY=a#b",
+			want:    []dotEnvLine{{key: "KEY", value: "a#b", raw: "KEY=a#b"}},
+		},
+		{
+			name:    "double-quoted escapes",
+			content: `KEY="a\nb\t\"c\"\\ \$HOME \x"`,
+			want:    []dotEnvLine{{key: "KEY", value: "a\nb\t\"c\"\\ \\$HOME \\x", quote: '"', raw: `KEY="a\nb\t\"c\"\\ \$HOME \x"`}},
+		},
+		{
+			name:    "single-quoted is literal",
+			content: `KEY='a\n # ${B}'`,
+			want:    []dotEnvLine{{key: "KEY", value: `a\n # ${B}`, quote: '\'', raw: `KEY='a\n # ${B}'`}},
+		},
+		{
+			name:    "quoted value with comment",
+			content: `KEY="a # b" # comment`,
+			want:    []dotEnvLine{{key: "KEY", value: "a # b", quote: '"', comment: "# comment", raw: `KEY="a # b" # comment`}},
+		},
+		{
+			name:    "multiline quoted value",
+			content: "KEY=\"line 1\nline 2\"\nNEXT=1",
+			want: []dotEnvLine{
+				{key: "KEY", value: "line 1\nline 2", quote: '"', raw: "KEY=\"line 1\nline 2\""},
+				{key: "NEXT", value: "1", raw: "NEXT=1"},
+			},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# header\n\n  KEY=value\n",
+			want: []dotEnvLine{
+				{raw: "# header"},
+				{raw: ""},
+				{key: "KEY", value: "value", raw: "  KEY=value"},
+			},
+		},
+		{
+			name:    "crlf line breaks",
+			content: "A=1\r\nB=2\r\n",
+			want: []dotEnvLine{
+				{key: "A", value: "1", raw: "A=1"},
+				{key: "B", value: "2", raw: "B=2"},
+			},
+		},
+		{
+			name:    "reference",
+			content: "DB_PASSWORD=ssm:///prod/db/password",
+			want:    []dotEnvLine{{key: "DB_PASSWORD", value: "ssm:///prod/db/password", raw: "DB_PASSWORD=ssm:///prod/db/password"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotEnv(tt.content)
+			if err != nil {
+				t.Fatalf("parseDotEnv() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseDotEnv() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"missing separator", "A=1\nKEY", "line 2: separator '=' is missing"},
+		{"invalid name", "1KEY=value", "line 1: invalid variable name '1KEY'"},
+		{"unterminated quote", "KEY=\"value\nNEXT=1", "line 1: unterminated quoted value of 'KEY'"},
+		{"characters after quote", `KEY="value" rest`, "line 1: unexpected characters after quoted value of 'KEY'"},
+		{"line number after multiline value", "A=\"1\n2\"\nB", "line 3: separator '=' is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDotEnv(tt.content)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseDotEnv() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDotEnvInterpolated(t *testing.T) {
+	lines, err := parseDotEnv("A=$B\nC=\"$B\"\nD='$B'\n# E=$B")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := dotEnvInterpolated(lines)
+	want := map[string]bool{"A": true, "C": true, "D": false}
+	if len(got) != len(want) {
+		t.Fatalf("dotEnvInterpolated() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("interpolated[%s] = %t, want %t", k, got[k], v)
+		}
+	}
+}
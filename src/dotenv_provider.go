@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemeSeparator separates provider scheme from the reference, like 'ssm://name'
+const schemeSeparator = "://"
+
+// SecretProvider resolves references of a single scheme into values
+type SecretProvider interface {
+	// Scheme is the reference prefix without '://', like 'ssm'
+	Scheme() string
+	// BatchSize is the maximum number of references passed to Resolve at once
+	BatchSize() int
+	// Concurrency is the maximum number of Resolve calls running at once
+	Concurrency() int
+	// Resolve returns values by references, references without values must be omitted
+	Resolve(ctx context.Context, refs []string) (map[string]string, error)
+}
+
+// SecretDescriber is implemented by providers which know metadata of resolved values
+type SecretDescriber interface {
+	// Describe returns metadata of the reference resolved before
+	Describe(ref string) (SecretMetadata, bool)
+}
+
+// SecretModifiable is implemented by providers which references accept modifiers like '?json=.db.host',
+// references of other providers like commands and file paths are taken as is, so '?' can be a part of those
+type SecretModifiable interface {
+	// Modifiable marks the provider, its references cant contain '?' themselves
+	Modifiable()
+}
+
+type SecretMetadata struct {
+	Version      int64
+	Type         string
+	LastModified time.Time
+}
+
+// secretProviderFactories creates providers of enabled schemes for every dotenv run, new providers should be registered here
+var secretProviderFactories = map[string]func(c *DotEnvCommand) SecretProvider{
+	ssmScheme:            newSsmProvider,
+	secretsManagerScheme: newSecretsManagerProvider,
+	fileScheme:           newFileProvider,
+	envScheme:            newEnvProvider,
+	cmdScheme:            newCmdProvider,
+	vaultScheme:          newVaultProvider,
+	sopsScheme:           newSopsProvider,
+}
+
+// defaultProviders are always enabled, the rest ones are enabled by --provider flag,
+// so existing values like 'file:///data/app.db' are kept as is and commands are never run unless asked for
+var defaultProviders = []string{ssmScheme, secretsManagerScheme}
+
+func optionalProviderNames() []string {
+	var names []string
+	for scheme := range secretProviderFactories {
+		if !containsString(defaultProviders, scheme) {
+			names = append(names, scheme)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *DotEnvCommand) configureProviderFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("provider", "Resolve references of the provider besides 'ssm://' and 'secretsmanager://', can be repeated, one of: "+strings.Join(optionalProviderNames(), ", ")).
+		PlaceHolder("SCHEME").
+		EnumsVar(&c.enabledProviders, optionalProviderNames()...)
+}
+
+// SecretProviders is the registry of providers by scheme
+type SecretProviders map[string]SecretProvider
+
+func (p SecretProviders) Register(provider SecretProvider) {
+	p[provider.Scheme()] = provider
+}
+
+// Lookup finds provider by value scheme and returns the reference without scheme
+func (p SecretProviders) Lookup(value string) (provider SecretProvider, ref string, isFound bool) {
+	i := strings.Index(value, schemeSeparator)
+	if i <= 0 {
+		return nil, "", false
+	}
+
+	provider, isFound = p[value[:i]]
+	return provider, value[i+len(schemeSeparator):], isFound
+}
+
+// Disabled finds scheme of the value which provider is known, but not enabled by --provider
+func (p SecretProviders) Disabled(value string) (scheme string, isDisabled bool) {
+	i := strings.Index(value, schemeSeparator)
+	if i <= 0 {
+		return "", false
+	}
+
+	scheme = value[:i]
+	_, isKnown := secretProviderFactories[scheme]
+	_, isEnabled := p[scheme]
+	return scheme, isKnown && !isEnabled
+}
+
+// refIndex maps every unique reference of a provider to env vars which use it
+type refIndex map[string][]string
+
+func (i refIndex) refs() []string {
+	refs := make([]string, 0, len(i))
+	for ref := range i {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// resolvedVar is the env var reference resolved by its provider
+type resolvedVar struct {
+	envVar   string
+	scheme   string
+	ref      string
+	value    string
+	isFound  bool
+	metadata *SecretMetadata
+	// file is the path the value should be written to
+	file string
+}
+
+func (c *DotEnvCommand) registerProviders() {
+	c.providers = SecretProviders{}
+	for _, scheme := range append(append([]string{}, defaultProviders...), c.enabledProviders...) {
+		c.providers.Register(secretProviderFactories[scheme](c))
+	}
+}
+
+// reference resolves provider of the value, SSM parameter name is determined by the template,
+// modifiers are stripped from the reference if the provider is SecretModifiable
+func (c *DotEnvCommand) reference(k string, v string) (provider SecretProvider, ref string, modifiers valueModifiers, isFound bool) {
+	parameter, err := c.parameter(k, v)
+	c.log.must(err)
+
+	if parameter != nil {
+		provider, ref, isFound = c.providers[ssmScheme], *parameter, true
+	} else if !c.isLiteral(k) {
+		// user template may not handle 'ssm://' references, those are resolved by the scheme then
+		provider, ref, isFound = c.providers.Lookup(v)
+	}
+
+	if !isFound {
+		return nil, "", modifiers, false
+	}
+
+	if _, ok := provider.(SecretModifiable); ok {
+		ref, modifiers, err = splitModifiers(ref)
+		c.log.must(err)
+	}
+
+	if provider.Scheme() == ssmScheme {
+		ref = c.lockedRef(k, ref)
+	}
+
+	return provider, ref, modifiers, true
+}
+
+// isReference is like reference, but locked versions are not looked up
+func (c *DotEnvCommand) isReference(k string, v string) bool {
+	parameter, err := c.parameter(k, v)
+	c.log.must(err)
+
+	if parameter != nil {
+		return true
+	}
+	if c.isLiteral(k) {
+		return false
+	}
+
+	_, _, isFound := c.providers.Lookup(v)
+	return isFound
+}
+
+// isLiteral is true for single-quoted values, those are never resolved by the scheme,
+// so a value like 'file:///data/app.db' is kept as is. The template still applies to them like before providers
+func (c *DotEnvCommand) isLiteral(k string) bool {
+	interpolated, ok := c.interpolated[k]
+	return ok && !interpolated
+}
+
+// disabledReferences warns about vars which refer to disabled providers, those values are kept as is
+func (c *DotEnvCommand) disabledReferences() []string {
+	var disabled []string
+	for _, k := range sortedKeys(c.dotEnvMap) {
+		if c.isLiteral(k) {
+			continue
+		}
+
+		values := []string{c.dotEnvMap[k]}
+		if c.interpolated[k] {
+			values = append(values, interpolations(c.dotEnvMap[k])...)
+		}
+		for _, v := range values {
+			if scheme, isDisabled := c.providers.Disabled(v); isDisabled {
+				c.log.Warning("Environment variable: %s refers to '%s' of disabled provider and is kept as is, use --provider %s to resolve it or single quotes to keep it", k, v, scheme)
+				disabled = append(disabled, fmt.Sprintf("%s (%s)", k, v))
+			}
+		}
+	}
+	return disabled
+}
+
+// processDotEnv replaces references by values resolved via their providers,
+// then values are interpolated, so those can refer to resolved vars and embed references
+func (c *DotEnvCommand) processDotEnv(ctx context.Context) error {
+	if err := c.expandReferences(); err != nil {
+		return err
+	}
+
+	if disabled := c.disabledReferences(); c.strict && len(disabled) > 0 {
+		return fmt.Errorf("strict mode, %d vars refer to disabled providers: %s", len(disabled), strings.Join(disabled, ", "))
+	}
+
+	embedded := c.embeddedReferences()
+	refs := make(map[string]string, len(c.dotEnvMap)+len(embedded))
+	for k, v := range c.dotEnvMap {
+		refs[k] = v
+	}
+	for k, v := range embedded {
+		refs[k] = v
+	}
+
+	resolved, err := c.resolveReferences(ctx, refs)
+	if err != nil {
+		return err
+	}
+	c.resolved = resolved
+
+	// resolved values are never expanded
+	expand := make(map[string]bool)
+	for k, v := range c.interpolated {
+		expand[k] = v
+	}
+
+	embeddedValues := make(map[string]string)
+	var missing []string
+	for _, r := range resolved {
+		value := r.value
+		if r.isFound && r.file != "" {
+			// the file is written by writeValueFiles once every check is passed
+			value = c.valueFilePath(r.file)
+		}
+		if !r.isFound {
+			value = valueNotExists
+			c.log.Warning("Value for %s%s%s not exists. Environment variable: %s", r.scheme, schemeSeparator, r.ref, r.envVar)
+			missing = append(missing, fmt.Sprintf("%s (%s%s%s)", r.envVar, r.scheme, schemeSeparator, r.ref))
+		}
+
+		if _, ok := embedded[r.envVar]; ok {
+			embeddedValues[r.envVar] = value
+		} else {
+			c.dotEnvMap[r.envVar] = value
+			expand[r.envVar] = false
+		}
+	}
+
+	// values loaded by path are resolved already
+	for k, v := range c.pathValues {
+		c.dotEnvMap[k] = v
+		expand[k] = false
+	}
+
+	if c.strict && len(missing) > 0 {
+		return fmt.Errorf("strict mode, %d referenced values not exist: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	return newInterpolator(c.log, c.dotEnvMap, expand, embeddedValues).expandAll()
+}
+
+// resolveReferences resolves every var which value is a reference, sorted by env var name
+func (c *DotEnvCommand) resolveReferences(ctx context.Context, dotEnvMap map[string]string) ([]resolvedVar, error) {
+	var resolved []resolvedVar
+	indexes := make(map[string]refIndex)
+	modifiers := make(map[string]valueModifiers)
+
+	for _, k := range sortedKeys(dotEnvMap) {
+		if provider, ref, m, isFound := c.reference(k, dotEnvMap[k]); isFound {
+			modifiers[k] = m
+			if indexes[provider.Scheme()] == nil {
+				indexes[provider.Scheme()] = refIndex{}
+			}
+			indexes[provider.Scheme()][ref] = append(indexes[provider.Scheme()][ref], k)
+		}
+	}
+
+	for scheme, index := range indexes {
+		provider := c.providers[scheme]
+		values, err := c.resolve(ctx, provider, index.refs())
+		if err != nil {
+			return nil, err
+		}
+
+		describer, _ := provider.(SecretDescriber)
+		for ref, envVars := range index {
+			value, isFound := values[ref]
+
+			var metadata *SecretMetadata
+			if describer != nil && isFound {
+				if m, ok := describer.Describe(ref); ok {
+					metadata = &m
+				}
+			}
+
+			// everything except plain SSM String parameters is secret
+			isSecret := metadata == nil || metadata.Type != ssm.ParameterTypeString
+			if isFound && isSecret {
+				c.log.Mask(value)
+			}
+
+			for _, envVar := range envVars {
+				transformed := value
+				if isFound {
+					if transformed, err = modifiers[envVar].transform(value); err != nil {
+						return nil, fmt.Errorf("%s (%s%s%s): %v", envVar, scheme, schemeSeparator, ref, err)
+					}
+					if isSecret {
+						c.log.Mask(transformed)
+					}
+				}
+
+				resolved = append(resolved, resolvedVar{
+					envVar:   envVar,
+					scheme:   scheme,
+					ref:      ref,
+					value:    transformed,
+					isFound:  isFound,
+					metadata: metadata,
+					file:     modifiers[envVar].file,
+				})
+			}
+		}
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		return resolved[i].envVar < resolved[j].envVar
+	})
+
+	return resolved, nil
+}
+
+// resolve requests unique references by batches via the pool of provider concurrency workers,
+// the first failed batch cancels the rest ones
+func (c *DotEnvCommand) resolve(ctx context.Context, provider SecretProvider, refs []string) (map[string]string, error) {
+	size := maxInt(provider.BatchSize(), 1)
+	var batches [][]string
+	for i := 0; i < len(refs); i += size {
+		j := i + size
+		if j > len(refs) {
+			j = len(refs)
+		}
+		batches = append(batches, refs[i:j])
+	}
+
+	workers := maxInt(provider.Concurrency(), 1)
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+
+	c.log.Debug("Provider: %s, refs: %v, batches: %v, workers: %v", provider.Scheme(), len(refs), len(batches), workers)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		values   = make(map[string]string)
+		queue    = make(chan []string)
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				var resolved map[string]string
+				err := c.withRetry(ctx, "Provider: "+provider.Scheme(), func() (err error) {
+					resolved, err = provider.Resolve(ctx, batch)
+					return err
+				})
+				c.log.Debug("Provider: %s, batch: %v, values: %v", provider.Scheme(), len(batch), len(resolved))
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				for k, v := range resolved {
+					values[k] = v
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, batch := range batches {
+		select {
+		case queue <- batch:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	return values, firstErr
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	fileScheme = "file"
+	envScheme  = "env"
+	cmdScheme  = "cmd"
+
+	// localBatchSize is the number of local references resolved at once, there is no API limits
+	localBatchSize = 100
+)
+
+// fileProvider reads values from files like 'file://secrets/db_password', relative paths are resolved from project path
+type fileProvider struct {
+	projectPath string
+}
+
+func newFileProvider(c *DotEnvCommand) SecretProvider {
+	return &fileProvider{projectPath: c.app.projectPath}
+}
+
+func (p *fileProvider) Scheme() string {
+	return fileScheme
+}
+
+func (p *fileProvider) BatchSize() int {
+	return localBatchSize
+}
+
+func (p *fileProvider) Concurrency() int {
+	return 1
+}
+
+func (p *fileProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, ref := range refs {
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.projectPath, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[ref] = trimNewline(string(content))
+	}
+
+	return values, nil
+}
+
+// envProvider reads values from the process environment like 'env://DB_PASSWORD'
+type envProvider struct{}
+
+func newEnvProvider(c *DotEnvCommand) SecretProvider {
+	return &envProvider{}
+}
+
+func (p *envProvider) Scheme() string {
+	return envScheme
+}
+
+func (p *envProvider) BatchSize() int {
+	return localBatchSize
+}
+
+func (p *envProvider) Concurrency() int {
+	return 1
+}
+
+func (p *envProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, ref := range refs {
+		if value, ok := os.LookupEnv(ref); ok {
+			values[ref] = value
+		}
+	}
+
+	return values, nil
+}
+
+// cmdProvider uses output of shell commands like 'cmd://pass show db/password' as values
+type cmdProvider struct {
+	log         *Log
+	projectPath string
+}
+
+func newCmdProvider(c *DotEnvCommand) SecretProvider {
+	return &cmdProvider{log: c.log, projectPath: c.app.projectPath}
+}
+
+func (p *cmdProvider) Scheme() string {
+	return cmdScheme
+}
+
+func (p *cmdProvider) BatchSize() int {
+	return 1
+}
+
+// commands may ask for input, so those are running one by one
+func (p *cmdProvider) Concurrency() int {
+	return 1
+}
+
+func (p *cmdProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, ref := range refs {
+		p.log.Debug("Running command: %s", ref)
+
+		stdout := new(bytes.Buffer)
+		cmd := exec.CommandContext(ctx, "sh", "-c", ref)
+		cmd.Dir = p.projectPath
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("command '%s' failed: %v", ref, err)
+		}
+		values[ref] = trimNewline(stdout.String())
+	}
+
+	return values, nil
+}
+
+// trimNewline removes single trailing line break which is usually added by editors and commands
+func trimNewline(value string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(value, "\n"), "\r")
+}
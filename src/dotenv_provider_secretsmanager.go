@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// secretsManagerScheme marks a value as AWS Secrets Manager reference like 'secretsmanager://secret-id#jsonKey@AWSPREVIOUS'
+const secretsManagerScheme = "secretsmanager"
+
+// Query parameters which select the version, secret IDs and ARNs can contain '@' but never '?' and '#'
+const (
+	secretVersionStage = "version-stage"
+	secretVersionId    = "version-id"
+)
+
+// awsVersionStage matches stages managed by AWS, only they can follow the secret ID or '#jsonKey',
+// since both can contain '@' like 'team@example.com/db#user@example.com'
+var awsVersionStage = regexp.MustCompile(`@(AWSCURRENT|AWSPREVIOUS|AWSPENDING)$`)
+
+type secretsManagerClient interface {
+	GetSecretValueWithContext(aws.Context, *secretsmanager.GetSecretValueInput, ...request.Option) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+type secretReference struct {
+	secretId     string
+	jsonKey      string
+	versionStage string
+	versionId    string
+}
+
+// secretsManagerProvider resolves secrets, every secret version is requested once per run
+type secretsManagerProvider struct {
+	log     *Log
+	client  secretsManagerClient
+	mu      sync.Mutex
+	secrets map[secretReference]*string
+}
+
+func newSecretsManagerProvider(c *DotEnvCommand) SecretProvider {
+	return &secretsManagerProvider{
+		log:     c.log,
+		client:  c.secretsManager,
+		secrets: make(map[secretReference]*string),
+	}
+}
+
+func (p *secretsManagerProvider) Scheme() string {
+	return secretsManagerScheme
+}
+
+// Modifiable: version is selected by query parameters which are kept
+func (p *secretsManagerProvider) Modifiable() {}
+
+func (p *secretsManagerProvider) BatchSize() int {
+	return 1
+}
+
+func (p *secretsManagerProvider) Concurrency() int {
+	return 4
+}
+
+func (p *secretsManagerProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, r := range refs {
+		ref, err := parseSecretReference(r)
+		if err != nil {
+			return nil, err
+		}
+
+		secret, err := p.secret(ctx, secretReference{secretId: ref.secretId, versionStage: ref.versionStage, versionId: ref.versionId})
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			continue
+		}
+
+		value, err := secretJsonValue(*secret, ref.jsonKey)
+		if err != nil {
+			return nil, fmt.Errorf("secret: %s, %v", ref.secretId, err)
+		}
+		values[r] = value
+	}
+
+	return values, nil
+}
+
+// parseSecretReference splits 'secret-id#jsonKey@AWSSTAGE', 'secret-id@AWSSTAGE' or 'secret-id?version-stage=STAGE#jsonKey',
+// version is selected by AWS stage suffix or by query, json key and version are optional
+func parseSecretReference(value string) (secretReference, error) {
+	ref := secretReference{}
+
+	if i := strings.Index(value, "#"); i >= 0 {
+		ref.jsonKey, ref.versionStage = splitAwsVersionStage(value[i+1:])
+		value = value[:i]
+	} else {
+		value, ref.versionStage = splitAwsVersionStage(value)
+	}
+
+	if i := strings.Index(value, "?"); i >= 0 {
+		query, err := url.ParseQuery(value[i+1:])
+		if err != nil {
+			return ref, fmt.Errorf("secret: %s, invalid query: %v", value, err)
+		}
+		value = value[:i]
+
+		for key := range query {
+			switch key {
+			case secretVersionStage, secretVersionId:
+				if ref.versionStage != "" {
+					return ref, fmt.Errorf("secret: %s, version is selected by both '@%s' and '%s'", value, ref.versionStage, key)
+				}
+			default:
+				return ref, fmt.Errorf("secret: %s, unsupported parameter '%s', only '%s' and '%s' are supported", value, key, secretVersionStage, secretVersionId)
+			}
+		}
+		ref.versionStage = query.Get(secretVersionStage)
+		ref.versionId = query.Get(secretVersionId)
+	}
+
+	ref.secretId = value
+	return ref, nil
+}
+
+// splitAwsVersionStage splits '@AWSCURRENT', '@AWSPREVIOUS' or '@AWSPENDING' suffix, other '@' are kept
+func splitAwsVersionStage(value string) (string, string) {
+	if m := awsVersionStage.FindStringSubmatchIndex(value); m != nil {
+		return value[:m[0]], value[m[2]:m[3]]
+	}
+	return value, ""
+}
+
+func (p *secretsManagerProvider) secret(ctx context.Context, ref secretReference) (*string, error) {
+	p.mu.Lock()
+	secret, ok := p.secrets[ref]
+	p.mu.Unlock()
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := p.getSecretValue(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.secrets[ref] = secret
+	p.mu.Unlock()
+	return secret, nil
+}
+
+func (p *secretsManagerProvider) getSecretValue(ctx context.Context, ref secretReference) (*string, error) {
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(ref.secretId),
+	}
+	if ref.versionStage != "" {
+		input.VersionStage = aws.String(ref.versionStage)
+	}
+	if ref.versionId != "" {
+		input.VersionId = aws.String(ref.versionId)
+	}
+
+	p.log.Debug("REQ: Secret: %s, version stage: %s, version id: %s", ref.secretId, ref.versionStage, ref.versionId)
+
+	resp, err := p.client.GetSecretValueWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.SecretString != nil {
+		return resp.SecretString, nil
+	}
+	return aws.String(string(resp.SecretBinary)), nil
+}
+
+// secretJsonValue picks the key out of JSON secret, the secret is returned as is when key is empty
+func secretJsonValue(secret string, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, key '%s' cant be picked", key)
+	}
+
+	if _, ok := fields[key]; !ok {
+		return "", fmt.Errorf("key '%s' not exists in the secret", key)
+	}
+
+	return jsonFieldValue(fields, key)
+}
+
+// jsonFieldValue returns string fields as is, other ones are encoded to JSON
+func jsonFieldValue(fields map[string]interface{}, key string) (string, error) {
+	if s, ok := fields[key].(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(fields[key])
+	return string(b), err
+}
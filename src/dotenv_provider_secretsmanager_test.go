@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// fakeSecretsManager returns secrets by 'id', 'id@STAGE' or 'id:VERSION_ID', default stage is AWSCURRENT
+type fakeSecretsManager struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (f *fakeSecretsManager) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+
+	key := aws.StringValue(input.SecretId)
+	switch {
+	case input.VersionId != nil:
+		key += ":" + aws.StringValue(input.VersionId)
+	case input.VersionStage != nil && aws.StringValue(input.VersionStage) != "AWSCURRENT":
+		key += "@" + aws.StringValue(input.VersionStage)
+	}
+
+	secret, ok := f.secrets[key]
+	if !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(secret)}, nil
+}
+
+func TestParseSecretReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    secretReference
+		wantErr bool
+	}{
+		{ref: "db", want: secretReference{secretId: "db"}},
+		{ref: "db#username", want: secretReference{secretId: "db", jsonKey: "username"}},
+		{ref: "team@example.com/db", want: secretReference{secretId: "team@example.com/db"}},
+		{ref: "db?version-stage=AWSPREVIOUS#password", want: secretReference{secretId: "db", jsonKey: "password", versionStage: "AWSPREVIOUS"}},
+		{ref: "db?version-id=EXAMPLE1-90ab", want: secretReference{secretId: "db", versionId: "EXAMPLE1-90ab"}},
+		{
+			ref:  "arn:aws:secretsmanager:eu-west-1:123456789012:secret:ops@team/db-AbCdEf?version-stage=AWSCURRENT",
+			want: secretReference{secretId: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:ops@team/db-AbCdEf", versionStage: "AWSCURRENT"},
+		},
+		{ref: "db#password@AWSPREVIOUS", want: secretReference{secretId: "db", jsonKey: "password", versionStage: "AWSPREVIOUS"}},
+		{ref: "db#user@example.com@AWSCURRENT", want: secretReference{secretId: "db", jsonKey: "user@example.com", versionStage: "AWSCURRENT"}},
+		// only AWS stages follow the json key, so keys containing '@' are kept
+		{ref: "db#user@example.com", want: secretReference{secretId: "db", jsonKey: "user@example.com"}},
+		{ref: "db#@CUSTOM", want: secretReference{secretId: "db", jsonKey: "@CUSTOM"}},
+		{ref: "db#password@", want: secretReference{secretId: "db", jsonKey: "password@"}},
+		{ref: "db?version-stage=CUSTOM#user@example.com", want: secretReference{secretId: "db", jsonKey: "user@example.com", versionStage: "CUSTOM"}},
+		{ref: "api-key@AWSPREVIOUS", want: secretReference{secretId: "api-key", versionStage: "AWSPREVIOUS"}},
+		// only AWS stages can follow the secret ID, so IDs containing '@' are kept
+		{ref: "team@CUSTOM", want: secretReference{secretId: "team@CUSTOM"}},
+		{ref: "db?stage=AWSCURRENT", wantErr: true},
+		{ref: "db?version-id=EXAMPLE1-90ab#password@AWSPREVIOUS", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSecretReference(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSecretReference(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseSecretReference(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestSecretsManagerProviderResolve(t *testing.T) {
+	client := &fakeSecretsManager{secrets: map[string]string{
+		"db":                       `{"username":"admin","password":"current","port":5432,"hosts":["a","b"],"user@example.com":"mail"}`,
+		"db@AWSPREVIOUS":           `{"username":"admin","password":"previous"}`,
+		"db:EXAMPLE1-90ab":         `{"password":"pinned"}`,
+		"api-key":                  "plain-secret",
+		"team@example.com/api-key": "team-secret",
+	}}
+	p := &secretsManagerProvider{log: &Log{ioWriter: io.Discard}, client: client, secrets: make(map[secretReference]*string)}
+
+	got, err := p.Resolve(context.Background(), []string{
+		"db#username",
+		"db#password",
+		"db#port",
+		"db#hosts",
+		"db#user@example.com",
+		"db?version-stage=AWSPREVIOUS#password",
+		"db?version-id=EXAMPLE1-90ab#password",
+		"db#password@AWSPREVIOUS",
+		"api-key",
+		"team@example.com/api-key",
+		"missing",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"db#username":                           "admin",
+		"db#password":                           "current",
+		"db#port":                               "5432",
+		"db#hosts":                              `["a","b"]`,
+		"db#user@example.com":                   "mail",
+		"db?version-stage=AWSPREVIOUS#password": "previous",
+		"db?version-id=EXAMPLE1-90ab#password":  "pinned",
+		"db#password@AWSPREVIOUS":               "previous",
+		"api-key":                               "plain-secret",
+		"team@example.com/api-key":              "team-secret",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+
+	// every secret version is requested once, keys are picked out of the same secret
+	if client.calls != 6 {
+		t.Errorf("GetSecretValue calls = %d, want 6", client.calls)
+	}
+}
+
+func TestSecretsManagerProviderErrors(t *testing.T) {
+	client := &fakeSecretsManager{secrets: map[string]string{"plain": "not-json", "db": `{"username":"admin"}`}}
+
+	for _, ref := range []string{"plain#key", "db#password", "db?stage=AWSCURRENT"} {
+		p := &secretsManagerProvider{log: &Log{ioWriter: io.Discard}, client: client, secrets: make(map[secretReference]*string)}
+		if _, err := p.Resolve(context.Background(), []string{ref}); err == nil {
+			t.Errorf("Resolve(%q) error is expected", ref)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sopsScheme = "sops"
+
+	// sopsBinary is used to decrypt files, keys are looked up by sops itself like SOPS_AGE_KEY_FILE or GnuPG keyring
+	sopsBinary = "sops"
+)
+
+// sopsProvider decrypts SOPS files like 'sops://secrets/dev.enc.yaml#db.password', the key is a path of the field,
+// whole decrypted file is the value if the key is omitted. Relative paths are resolved from project path
+type sopsProvider struct {
+	log         *Log
+	projectPath string
+}
+
+func newSopsProvider(c *DotEnvCommand) SecretProvider {
+	return &sopsProvider{log: c.log, projectPath: c.app.projectPath}
+}
+
+func (p *sopsProvider) Scheme() string {
+	return sopsScheme
+}
+
+func (p *sopsProvider) BatchSize() int {
+	return localBatchSize
+}
+
+// every file is decrypted once per batch, gpg-agent may ask for passphrase, so files are decrypted one by one
+func (p *sopsProvider) Concurrency() int {
+	return 1
+}
+
+func (p *sopsProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+	decrypted := make(map[string]string)
+
+	for _, ref := range refs {
+		file, key, _ := strings.Cut(ref, "#")
+
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.projectPath, path)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		// fields are picked out of JSON, so the file is decrypted the same way for any key
+		outputType := "json"
+		if key == "" {
+			outputType = ""
+		}
+
+		cacheKey := outputType + ":" + path
+		content, ok := decrypted[cacheKey]
+		if !ok {
+			var err error
+			if content, err = p.decrypt(ctx, path, outputType); err != nil {
+				return nil, err
+			}
+			decrypted[cacheKey] = content
+		}
+
+		if key == "" {
+			values[ref] = trimNewline(content)
+			continue
+		}
+
+		value, err := jsonPathValue(content, "."+key)
+		if err != nil {
+			p.log.Warning("SOPS file: %s, %v, keys are dot separated paths of the field like '#db.password'", file, err)
+			continue
+		}
+		values[ref] = value
+	}
+
+	return values, nil
+}
+
+func (p *sopsProvider) decrypt(ctx context.Context, path string, outputType string) (string, error) {
+	args := []string{"--decrypt"}
+	if outputType != "" {
+		args = append(args, "--output-type", outputType)
+	}
+	args = append(args, path)
+
+	p.log.Debug("Decrypting SOPS file: %s", path)
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, sopsBinary, args...)
+	cmd.Dir = p.projectPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("SOPS file '%s' cant be decrypted, '%s' binary is required in PATH, see https://github.com/getsops/sops", path, sopsBinary)
+		}
+		return "", fmt.Errorf("SOPS file '%s' cant be decrypted: %v %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
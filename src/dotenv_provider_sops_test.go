@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeSops puts 'sops' script on PATH, it prints the JSON or raw content and records its arguments
+func fakeSops(t *testing.T) (calls string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake sops is a shell script")
+	}
+
+	dir := t.TempDir()
+	calls = filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+echo "$@" >> '` + calls + `'
+case "$*" in
+  *missing.enc.yaml) echo "Failed to get the data key" >&2; exit 128 ;;
+  *"--output-type json"*) echo '{"db":{"password":"PaSsW0rd","port":5432}}' ;;
+  *) printf 'db:\n  password: PaSsW0rd\n' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, sopsBinary), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return calls
+}
+
+func TestSopsProviderResolve(t *testing.T) {
+	calls := fakeSops(t)
+
+	out := new(bytes.Buffer)
+	projectPath := t.TempDir()
+	for _, name := range []string{"dev.enc.yaml", "missing.enc.yaml"} {
+		if err := os.WriteFile(filepath.Join(projectPath, name), []byte("encrypted"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &sopsProvider{log: &Log{ioWriter: out}, projectPath: projectPath}
+
+	got, err := p.Resolve(context.Background(), []string{
+		"dev.enc.yaml#db.password",
+		"dev.enc.yaml#db.port",
+		"dev.enc.yaml#db.user",
+		"dev.enc.yaml",
+		"absent.enc.yaml#db.password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"dev.enc.yaml#db.password": "PaSsW0rd",
+		"dev.enc.yaml#db.port":     "5432",
+		"dev.enc.yaml":             "db:\n  password: PaSsW0rd",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+
+	// missing key is not silent
+	if !strings.Contains(out.String(), "[WARNING]") || !strings.Contains(out.String(), "key 'user' is missing") {
+		t.Errorf("missing key warning is expected, got:\n%s", out.String())
+	}
+
+	// the file is decrypted once per output type
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectPath, "dev.enc.yaml")
+	if want := "--decrypt --output-type json " + path + "\n--decrypt " + path + "\n"; string(content) != want {
+		t.Errorf("sops calls =\n%s\nwant\n%s", content, want)
+	}
+
+	if _, err := p.Resolve(context.Background(), []string{"missing.enc.yaml#db.password"}); err == nil ||
+		!strings.Contains(err.Error(), "Failed to get the data key") {
+		t.Errorf("Resolve() error = %v, sops error is expected", err)
+	}
+}
+
+func TestSopsProviderMissingBinary(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "dev.enc.yaml"), []byte("encrypted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := &sopsProvider{log: &Log{ioWriter: new(bytes.Buffer)}, projectPath: projectPath}
+
+	_, err := p.Resolve(context.Background(), []string{"dev.enc.yaml#db.password"})
+	if err == nil || !strings.Contains(err.Error(), "binary is required in PATH") {
+		t.Errorf("Resolve() error = %v, missing binary hint is expected", err)
+	}
+}
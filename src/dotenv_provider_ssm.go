@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ssmScheme = "ssm"
+
+	// ssmSelectorSeparator separates parameter name and version or label selector like 'name:5' or 'name:prod'
+	ssmSelectorSeparator = ":"
+)
+
+// ssmSelectorPattern matches version number or label, labels can contain letters, numbers, '.', '-' and '_'
+var ssmSelectorPattern = regexp.MustCompile(`^([0-9]+|[A-Za-z_.-][A-Za-z0-9_.-]*)$`)
+
+const (
+	ssmArnPrefix    = "arn:"
+	ssmArnParameter = ":parameter/"
+)
+
+type ssmClient interface {
+	GetParametersWithContext(aws.Context, *ssm.GetParametersInput, ...request.Option) (*ssm.GetParametersOutput, error)
+	GetParametersByPathWithContext(aws.Context, *ssm.GetParametersByPathInput, ...request.Option) (*ssm.GetParametersByPathOutput, error)
+	PutParameterWithContext(aws.Context, *ssm.PutParameterInput, ...request.Option) (*ssm.PutParameterOutput, error)
+}
+
+// ssmProvider resolves AWS Parameter Store parameters like 'ssm:///production/db/password'
+type ssmProvider struct {
+	log         *Log
+	client      ssmClient
+	decrypt     bool
+	batchSize   int
+	concurrency int
+	cache       *secretCache
+	mu          sync.Mutex
+	metadata    map[string]SecretMetadata
+}
+
+func newSsmProvider(c *DotEnvCommand) SecretProvider {
+	return &ssmProvider{
+		log:         c.log,
+		client:      c.ssm,
+		decrypt:     c.decrypt,
+		batchSize:   c.batchSize,
+		concurrency: c.concurrency,
+		cache:       c.secretCache,
+		metadata:    make(map[string]SecretMetadata),
+	}
+}
+
+func (p *ssmProvider) Scheme() string {
+	return ssmScheme
+}
+
+// Modifiable: parameter names cant contain '?'
+func (p *ssmProvider) Modifiable() {}
+
+func (p *ssmProvider) BatchSize() int {
+	return p.batchSize
+}
+
+func (p *ssmProvider) Concurrency() int {
+	return p.concurrency
+}
+
+func (p *ssmProvider) Resolve(ctx context.Context, names []string) (map[string]string, error) {
+	if p.cache == nil {
+		return p.getParameters(ctx, names, p.decrypt)
+	}
+
+	values := make(map[string]string)
+	var missed []string
+	for _, name := range names {
+		if entry, ok := p.cache.get(p.cacheKey(name)); ok {
+			p.log.Debug("Parameter: %s, version: %d, cached", name, entry.Metadata.Version)
+			values[name] = entry.Value
+			p.mu.Lock()
+			p.metadata[name] = entry.Metadata
+			p.mu.Unlock()
+			continue
+		}
+		missed = append(missed, name)
+	}
+
+	if len(missed) == 0 {
+		return values, nil
+	}
+
+	fetched, err := p.getParameters(ctx, missed, p.decrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range fetched {
+		values[name] = value
+		metadata, _ := p.Describe(name)
+		if err := p.cache.put(p.cacheKey(name), value, metadata); err != nil {
+			p.log.Warning("Parameter: %s cant be cached: %v", name, err)
+		}
+	}
+
+	return values, nil
+}
+
+// cacheKey includes decrypt flag, SecureString values differ with and without decryption
+func (p *ssmProvider) cacheKey(name string) string {
+	return fmt.Sprintf("%s://%s?decrypt=%t", ssmScheme, name, p.decrypt)
+}
+
+func (p *ssmProvider) Describe(name string) (SecretMetadata, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	metadata, ok := p.metadata[name]
+	return metadata, ok
+}
+
+func (p *ssmProvider) getParameters(ctx context.Context, names []string, decrypt bool) (map[string]string, error) {
+	values := make(map[string]string)
+
+	input := &ssm.GetParametersInput{
+		WithDecryption: aws.Bool(decrypt),
+	}
+
+	for _, n := range names {
+		input.Names = append(input.Names, aws.String(n))
+	}
+
+	p.log.Debug("REQ: Batch, input.Name: %v", len(input.Names))
+
+	resp, err := p.client.GetParametersWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.Debug("RESP: Batch, resp.Parameters: %v, resp.InvalidParameters: %v", len(resp.Parameters), len(resp.InvalidParameters))
+
+	// parameter names are case sensitive, so those must match exactly
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, param := range resp.Parameters {
+		name := *param.Name
+		if selector := aws.StringValue(param.Selector); selector != "" {
+			name = name + ssmSelectorSeparator + strings.TrimPrefix(selector, ssmSelectorSeparator)
+		}
+
+		p.log.Debug("Parameter: %s, version: %d", name, aws.Int64Value(param.Version))
+
+		values[name] = *param.Value
+		p.metadata[name] = SecretMetadata{
+			Version:      aws.Int64Value(param.Version),
+			Type:         aws.StringValue(param.Type),
+			LastModified: aws.TimeValue(param.LastModifiedDate),
+		}
+	}
+
+	return values, nil
+}
+
+// ssmSelector splits parameter reference into name and selector, selector is empty if it's not specified.
+// Only version number or label is a selector, so ARN like 'arn:aws:ssm:us-east-1:123456789012:parameter/app/db'
+// is a name, its selector must follow the parameter path like 'arn:...:parameter/app/db:5'
+func ssmSelector(ref string) (name string, selector string) {
+	i := strings.LastIndex(ref, ssmSelectorSeparator)
+	if i < 0 || !ssmSelectorPattern.MatchString(ref[i+1:]) {
+		return ref, ""
+	}
+	if strings.HasPrefix(ref, ssmArnPrefix) && !strings.Contains(ref[:i], ssmArnParameter) {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// ssmVersionRef pins parameter reference to the version
+func ssmVersionRef(name string, version int64) string {
+	return name + ssmSelectorSeparator + strconv.FormatInt(version, 10)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// maxGetParametersNames is the limit of names per GetParameters request
+const maxGetParametersNames = 10
+
+// fakeSsm stores parameters by exact names like Parameter Store does, names are case sensitive
+// fakeSsm answers like SSM does: selected parameters are returned by name with ':5' or ':label' selector,
+// values of versions and labels are keyed by 'name:5' and 'name:label'
+type fakeSsm struct {
+	parameters map[string]string
+	// versions are current versions by name, 1 by default
+	versions  map[string]int64
+	mu        sync.Mutex
+	requested []string
+	batches   []int
+}
+
+func (f *fakeSsm) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	if len(input.Names) > maxGetParametersNames {
+		return nil, fmt.Errorf("ValidationException: %d names, at most %d are allowed", len(input.Names), maxGetParametersNames)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, len(input.Names))
+
+	out := &ssm.GetParametersOutput{}
+	for _, n := range aws.StringValueSlice(input.Names) {
+		f.requested = append(f.requested, n)
+		value, ok := f.parameters[n]
+		if !ok {
+			out.InvalidParameters = append(out.InvalidParameters, aws.String(n))
+			continue
+		}
+
+		name, selector := ssmSelector(n)
+		version, ok := f.versions[name]
+		if !ok {
+			version = 1
+		}
+		if v, err := strconv.ParseInt(selector, 10, 64); err == nil {
+			version = v
+		}
+
+		parameter := &ssm.Parameter{
+			Name:    aws.String(name),
+			Value:   aws.String(value),
+			Type:    aws.String(ssm.ParameterTypeSecureString),
+			Version: aws.Int64(version),
+		}
+		if selector != "" {
+			parameter.Selector = aws.String(ssmSelectorSeparator + selector)
+		}
+		out.Parameters = append(out.Parameters, parameter)
+	}
+	return out, nil
+}
+
+// GetParametersByPathWithContext returns every parameter under the path in a single page
+func (f *fakeSsm) GetParametersByPathWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, opts ...request.Option) (*ssm.GetParametersByPathOutput, error) {
+	out := &ssm.GetParametersByPathOutput{}
+	for _, n := range sortedKeys(f.parameters) {
+		if strings.HasPrefix(n, aws.StringValue(input.Path)+"/") {
+			out.Parameters = append(out.Parameters, &ssm.Parameter{
+				Name:  aws.String(n),
+				Value: aws.String(f.parameters[n]),
+				Type:  aws.String(ssm.ParameterTypeSecureString),
+			})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSsm) PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error) {
+	return &ssm.PutParameterOutput{Version: aws.Int64(1)}, nil
+}
+
+func TestResolveReferences(t *testing.T) {
+	many := map[string]string{}
+	manyVars := map[string]string{}
+	manyWant := map[string]string{}
+	for i := 0; i < 23; i++ {
+		name := fmt.Sprintf("/p/param%02d", i)
+		many[name] = fmt.Sprintf("value%02d", i)
+		manyVars[fmt.Sprintf("VAR_%02d", i)] = "ssm://" + name
+		manyWant[fmt.Sprintf("VAR_%02d", i)] = many[name]
+	}
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		vars       map[string]string
+		want       map[string]string
+		missing    []string
+		requested  int
+		batches    int
+	}{
+		{
+			name:       "duplicate references are requested once",
+			parameters: map[string]string{"/p/db/password": "secret"},
+			vars:       map[string]string{"DB_PASSWORD": "ssm:///p/db/password", "DATABASE_PASSWORD": "ssm:///p/db/password"},
+			want:       map[string]string{"DB_PASSWORD": "secret", "DATABASE_PASSWORD": "secret"},
+			requested:  1,
+			batches:    1,
+		},
+		{
+			name:       "case different names are different parameters",
+			parameters: map[string]string{"/p/Case": "upper", "/p/case": "lower"},
+			vars:       map[string]string{"UPPER": "ssm:///p/Case", "LOWER": "ssm:///p/case"},
+			want:       map[string]string{"UPPER": "upper", "LOWER": "lower"},
+			requested:  2,
+			batches:    1,
+		},
+		{
+			name:       "only case different name not exists",
+			parameters: map[string]string{"/p/case": "lower"},
+			vars:       map[string]string{"UPPER": "ssm:///p/CASE"},
+			want:       map[string]string{},
+			missing:    []string{"UPPER"},
+			requested:  1,
+			batches:    1,
+		},
+		{
+			name:       "more than 10 references are split into batches",
+			parameters: many,
+			vars:       manyVars,
+			want:       manyWant,
+			requested:  23,
+			batches:    3,
+		},
+		{
+			name:       "invalid parameters are missing",
+			parameters: map[string]string{"/p/db/host": "db1"},
+			vars:       map[string]string{"DB_HOST": "ssm:///p/db/host", "DB_USER": "ssm:///p/db/user", "DB_NAME": "app"},
+			want:       map[string]string{"DB_HOST": "db1"},
+			missing:    []string{"DB_USER"},
+			requested:  2,
+			batches:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSsm{parameters: tt.parameters}
+			c := testDotEnvCommand(t)
+			c.ssm = client
+			c.concurrency = 2
+			c.registerProviders()
+
+			resolved, err := c.resolveReferences(context.Background(), tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]string{}
+			var missing []string
+			for _, r := range resolved {
+				if r.isFound {
+					got[r.envVar] = r.value
+				} else {
+					missing = append(missing, r.envVar)
+				}
+			}
+			sort.Strings(missing)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolved = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("missing = %v, want %v", missing, tt.missing)
+			}
+			if len(client.requested) != tt.requested {
+				t.Errorf("requested names = %v, want %d", client.requested, tt.requested)
+			}
+			if len(client.batches) != tt.batches {
+				t.Errorf("batches = %v, want %d", client.batches, tt.batches)
+			}
+		})
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	parameters := map[string]string{}
+	var source []string
+	for i := 0; i < 23; i++ {
+		name := fmt.Sprintf("/p/param%02d", i)
+		// params of the first and the last batch are missing
+		if i != 3 && i != 22 {
+			parameters[name] = fmt.Sprintf("value%02d", i)
+		}
+		source = append(source, fmt.Sprintf("VAR_%02d=ssm://%s", i, name))
+	}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		source  []string
+		missing []string
+		err     string
+	}{
+		{
+			name:   "missing values of several batches are collected into one error",
+			strict: true,
+			source: source,
+			err:    "strict mode, 2 referenced values not exist: VAR_03 (ssm:///p/param03), VAR_22 (ssm:///p/param22)",
+		},
+		{
+			name:   "every value exists",
+			strict: true,
+			source: append(append([]string{}, source[:3]...), source[4:22]...),
+		},
+		{
+			name:    "missing values are kept without strict mode",
+			source:  source,
+			missing: []string{"VAR_03", "VAR_22"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSsm{parameters: parameters}
+			c := withSource(t, testDotEnvCommand(t), strings.Join(tt.source, "\n"))
+			c.ssm = client
+			c.strict = tt.strict
+			c.registerProviders()
+
+			err := c.processDotEnv(context.Background())
+			if len(client.batches) != 3 {
+				t.Errorf("batches = %v, want 3", client.batches)
+			}
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("processDotEnv() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range tt.missing {
+				if c.dotEnvMap[k] != valueNotExists {
+					t.Errorf("%s = %q, want %q", k, c.dotEnvMap[k], valueNotExists)
+				}
+			}
+		})
+	}
+}
+
+func TestRefIndex(t *testing.T) {
+	index := refIndex{"/p/b": {"B"}, "/p/a": {"A", "A2"}, "/p/A": {"UPPER_A"}}
+	if got, want := index.refs(), []string{"/p/A", "/p/a", "/p/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("refs() = %v, want %v", got, want)
+	}
+}
+
+func TestSingleQuotedValuesAreLiteral(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), strings.Join([]string{
+		"DATABASE_URL='file:///data/app.db'",
+		"TOKEN_FILE=file://token.txt",
+		"HOME_DIR='env://HOME'",
+		// template applies regardless of quotes
+		"DB_HOST='ssm:///p/db/host'",
+	}, "\n"))
+	if err := os.WriteFile(filepath.Join(c.app.projectPath, "token.txt"), []byte("t0ken\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c.ssm = &fakeSsm{parameters: map[string]string{"/p/db/host": "db1"}}
+	c.strict = true
+	c.enabledProviders = []string{fileScheme, envScheme}
+	c.registerProviders()
+
+	if err := c.processDotEnv(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DATABASE_URL": "file:///data/app.db",
+		"TOKEN_FILE":   "t0ken",
+		"HOME_DIR":     "env://HOME",
+		"DB_HOST":      "db1",
+	}
+	if !reflect.DeepEqual(c.dotEnvMap, want) {
+		t.Errorf("dotEnvMap = %v, want %v", c.dotEnvMap, want)
+	}
+}
+
+func TestProvidersAreOptIn(t *testing.T) {
+	pwned := filepath.Join(t.TempDir(), "pwned")
+	source := strings.Join([]string{
+		"DATABASE_URL='file:///data/app.db'",
+		"HOME_DIR=env://HOME",
+		"TOKEN=cmd://touch " + pwned,
+		`EMBEDDED="x${cmd://touch ` + pwned + `}"`,
+		"API_SECRET=vault://secret/app#api",
+		"DB_HOST=ssm:///p/db/host",
+		"REDIS_URL=redis://cache",
+	}, "\n")
+
+	tests := []struct {
+		name   string
+		strict bool
+		err    bool
+	}{
+		{"disabled references are kept as is", false, false},
+		{"strict mode fails on disabled references", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withSource(t, testDotEnvCommand(t), source)
+			output := new(bytes.Buffer)
+			c.log.ioWriter = output
+			c.ssm = &fakeSsm{parameters: map[string]string{"/p/db/host": "db1"}}
+			c.strict = tt.strict
+			c.registerProviders()
+
+			err := c.processDotEnv(context.Background())
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), "API_SECRET (vault://secret/app#api)") {
+					t.Errorf("processDotEnv() error = %v, disabled providers error is expected", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.err {
+				want := map[string]string{
+					"DATABASE_URL": "file:///data/app.db",
+					"HOME_DIR":     "env://HOME",
+					"TOKEN":        "cmd://touch " + pwned,
+					"EMBEDDED":     "x${cmd://touch " + pwned + "}",
+					"API_SECRET":   "vault://secret/app#api",
+					"DB_HOST":      "db1",
+					"REDIS_URL":    "redis://cache",
+				}
+				if !reflect.DeepEqual(c.dotEnvMap, want) {
+					t.Errorf("dotEnvMap = %v, want %v", c.dotEnvMap, want)
+				}
+			}
+
+			// every var of a disabled provider is warned about, literal and unknown schemes are not
+			for _, k := range []string{"HOME_DIR", "TOKEN", "EMBEDDED", "API_SECRET"} {
+				if !strings.Contains(output.String(), "Environment variable: "+k+" refers to") {
+					t.Errorf("no warning about %s: %s", k, output.String())
+				}
+			}
+			for _, k := range []string{"DATABASE_URL", "REDIS_URL", "DB_HOST"} {
+				if strings.Contains(output.String(), "Environment variable: "+k+" refers to") {
+					t.Errorf("unexpected warning about %s: %s", k, output.String())
+				}
+			}
+			if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+				t.Errorf("command of disabled provider is run: %v", err)
+			}
+		})
+	}
+}
+
+func TestSsmSelector(t *testing.T) {
+	tests := []struct {
+		ref      string
+		name     string
+		selector string
+	}{
+		{"/app/db", "/app/db", ""},
+		{"/app/db:5", "/app/db", "5"},
+		{"/app/db:stable", "/app/db", "stable"},
+		{"production.service.db:release-1.2_a", "production.service.db", "release-1.2_a"},
+		{"arn:aws:ssm:us-east-1:123456789012:parameter/app/db", "arn:aws:ssm:us-east-1:123456789012:parameter/app/db", ""},
+		{"arn:aws:ssm:us-east-1:123456789012:parameter/db", "arn:aws:ssm:us-east-1:123456789012:parameter/db", ""},
+		{"arn:aws:ssm:us-east-1:123456789012:parameter/app/db:5", "arn:aws:ssm:us-east-1:123456789012:parameter/app/db", "5"},
+		{"arn:aws:ssm:us-east-1:123456789012:parameter/app/db:stable", "arn:aws:ssm:us-east-1:123456789012:parameter/app/db", "stable"},
+		{"arn:aws:ssm:us-east-1:123456789012", "arn:aws:ssm:us-east-1:123456789012", ""},
+	}
+
+	for _, tt := range tests {
+		if name, selector := ssmSelector(tt.ref); name != tt.name || selector != tt.selector {
+			t.Errorf("ssmSelector(%q) = %q, %q, want %q, %q", tt.ref, name, selector, tt.name, tt.selector)
+		}
+	}
+}
+
+func TestResolveSelectors(t *testing.T) {
+	arn := "arn:aws:ssm:us-east-1:123456789012:parameter/p/shared"
+	client := &fakeSsm{
+		parameters: map[string]string{
+			"/p/db":        "current",
+			"/p/db:5":      "fifth",
+			"/p/db:stable": "stable",
+			"/p/db:3":      "third",
+			"/p/api":       "api",
+			arn:            "shared",
+			arn + ":2":     "shared second",
+		},
+		versions: map[string]int64{"/p/db": 7},
+	}
+
+	c := testDotEnvCommand(t)
+	c.ssm = client
+	c.registerProviders()
+	c.locked = true
+	c.lock = lockFile{
+		"LOCKED":        {Parameter: "/p/db", Version: 3},
+		"LOCKED_PINNED": {Parameter: "/p/db", Version: 3},
+		"LOCKED_ARN":    {Parameter: arn, Version: 2},
+	}
+
+	resolved, err := c.resolveReferences(context.Background(), map[string]string{
+		"CURRENT":       "ssm:///p/db",
+		"VERSION":       "ssm:///p/db:5",
+		"LABEL":         "ssm:///p/db:stable",
+		"LOCKED":        "ssm:///p/db",
+		"LOCKED_PINNED": "ssm:///p/db:5",
+		"ARN":           "ssm://" + arn,
+		"LOCKED_ARN":    "ssm://" + arn,
+		"NOT_LOCKED":    "ssm:///p/api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		value   string
+		version int64
+	}
+	got := map[string]result{}
+	for _, r := range resolved {
+		if !r.isFound || r.metadata == nil {
+			t.Errorf("%s (%s) is not resolved", r.envVar, r.ref)
+			continue
+		}
+		got[r.envVar] = result{r.value, r.metadata.Version}
+	}
+
+	want := map[string]result{
+		"CURRENT":       {"current", 7},
+		"VERSION":       {"fifth", 5},
+		"LABEL":         {"stable", 7},
+		"LOCKED":        {"third", 3},
+		"LOCKED_PINNED": {"fifth", 5},
+		"ARN":           {"shared", 1},
+		"LOCKED_ARN":    {"shared second", 2},
+		"NOT_LOCKED":    {"api", 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolved = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// vaultScheme marks a value as HashiCorp Vault KV reference like 'vault://mount/path#key?version=N'
+	vaultScheme = "vault"
+
+	vaultAddrEnvVar      = "VAULT_ADDR"
+	vaultTokenEnvVar     = "VAULT_TOKEN"
+	vaultNamespaceEnvVar = "VAULT_NAMESPACE"
+	vaultRoleIdEnvVar    = "VAULT_ROLE_ID"
+	vaultSecretIdEnvVar  = "VAULT_SECRET_ID"
+	vaultApproleEnvVar   = "VAULT_APPROLE_MOUNT"
+
+	defaultVaultApproleMount = "approle"
+	defaultVaultTimeout      = 30 * time.Second
+)
+
+type vaultReference struct {
+	path    string
+	key     string
+	version int
+}
+
+type vaultMount struct {
+	path    string
+	version string
+}
+
+// vaultProvider reads secrets from KV v1 and v2 engines via Vault HTTP API
+type vaultProvider struct {
+	log        *Log
+	addr       string
+	token      string
+	namespace  string
+	roleId     string
+	secretId   string
+	approle    string
+	httpClient *http.Client
+	mu         sync.Mutex
+	mounts     map[string]vaultMount
+}
+
+func newVaultProvider(c *DotEnvCommand) SecretProvider {
+	approle := os.Getenv(vaultApproleEnvVar)
+	if approle == "" {
+		approle = defaultVaultApproleMount
+	}
+
+	return &vaultProvider{
+		log:        c.log,
+		addr:       strings.TrimSuffix(os.Getenv(vaultAddrEnvVar), "/"),
+		token:      os.Getenv(vaultTokenEnvVar),
+		namespace:  os.Getenv(vaultNamespaceEnvVar),
+		roleId:     os.Getenv(vaultRoleIdEnvVar),
+		secretId:   os.Getenv(vaultSecretIdEnvVar),
+		approle:    approle,
+		httpClient: &http.Client{Timeout: defaultVaultTimeout},
+		mounts:     make(map[string]vaultMount),
+	}
+}
+
+func (p *vaultProvider) Scheme() string {
+	return vaultScheme
+}
+
+// Modifiable: version is selected by '?version=N' which is kept
+func (p *vaultProvider) Modifiable() {}
+
+func (p *vaultProvider) BatchSize() int {
+	return 1
+}
+
+func (p *vaultProvider) Concurrency() int {
+	return 4
+}
+
+func (p *vaultProvider) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	if p.addr == "" {
+		return nil, fmt.Errorf("%s must be set to resolve Vault references", vaultAddrEnvVar)
+	}
+
+	for _, r := range refs {
+		ref, err := parseVaultReference(r)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := p.read(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+
+		if ref.key == "" {
+			b, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			values[r] = string(b)
+			continue
+		}
+
+		if _, ok := data[ref.key]; !ok {
+			continue
+		}
+		value, err := jsonFieldValue(data, ref.key)
+		if err != nil {
+			return nil, err
+		}
+		values[r] = value
+	}
+
+	return values, nil
+}
+
+// parseVaultReference splits 'mount/path#key?version=N', query can be placed before the key as well
+func parseVaultReference(value string) (vaultReference, error) {
+	ref := vaultReference{}
+
+	if i := strings.Index(value, "#"); i >= 0 {
+		ref.key = value[i+1:]
+		value = value[:i]
+	}
+
+	query := ""
+	if i := strings.Index(value, "?"); i >= 0 {
+		query = value[i+1:]
+		value = value[:i]
+	} else if i := strings.Index(ref.key, "?"); i >= 0 {
+		query = ref.key[i+1:]
+		ref.key = ref.key[:i]
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return ref, fmt.Errorf("vault reference '%s': %v", value, err)
+	}
+	if v := params.Get("version"); v != "" {
+		if ref.version, err = strconv.Atoi(v); err != nil {
+			return ref, fmt.Errorf("vault reference '%s': version must be a number", value)
+		}
+	}
+
+	ref.path = strings.Trim(value, "/")
+	if !strings.Contains(ref.path, "/") {
+		return ref, fmt.Errorf("vault reference '%s' must be like 'mount/path'", value)
+	}
+
+	return ref, nil
+}
+
+// read returns secret data, nil means the secret not exists
+func (p *vaultProvider) read(ctx context.Context, ref vaultReference) (map[string]interface{}, error) {
+	mount, err := p.mount(ctx, ref.path)
+	if err != nil {
+		return nil, err
+	}
+	secretPath := strings.TrimPrefix(ref.path, mount.path)
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+
+	if mount.version == "2" {
+		query := url.Values{}
+		if ref.version > 0 {
+			query.Set("version", strconv.Itoa(ref.version))
+		}
+
+		var kv2 struct {
+			Data struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}
+		found, err := p.request(ctx, http.MethodGet, "/v1/"+mount.path+"data/"+secretPath, query, nil, &kv2)
+		if err != nil || !found {
+			return nil, err
+		}
+		return kv2.Data.Data, nil
+	}
+
+	if ref.version > 0 {
+		return nil, fmt.Errorf("vault mount '%s' is KV v1 and doesn't support versions", mount.path)
+	}
+
+	found, err := p.request(ctx, http.MethodGet, "/v1/"+mount.path+secretPath, nil, nil, &resp)
+	if err != nil || !found {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// mount detects the secret engine mount of the path and its KV version
+func (p *vaultProvider) mount(ctx context.Context, path string) (vaultMount, error) {
+	p.mu.Lock()
+	for prefix, mount := range p.mounts {
+		if strings.HasPrefix(path, prefix) {
+			p.mu.Unlock()
+			return mount, nil
+		}
+	}
+	p.mu.Unlock()
+
+	var resp struct {
+		Data struct {
+			Path    string `json:"path"`
+			Options struct {
+				Version string `json:"version"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	found, err := p.request(ctx, http.MethodGet, "/v1/sys/internal/ui/mounts/"+path, nil, nil, &resp)
+	if err != nil {
+		return vaultMount{}, err
+	}
+	if !found || resp.Data.Path == "" {
+		return vaultMount{}, fmt.Errorf("vault mount of '%s' not found", path)
+	}
+
+	mount := vaultMount{path: resp.Data.Path, version: resp.Data.Options.Version}
+	p.log.Debug("Vault mount: %s, KV version: %s", mount.path, mount.version)
+
+	p.mu.Lock()
+	p.mounts[mount.path] = mount
+	p.mu.Unlock()
+	return mount, nil
+}
+
+// authToken returns VAULT_TOKEN, logs in via AppRole or uses token stored by Vault CLI
+func (p *vaultProvider) authToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" {
+		return p.token, nil
+	}
+
+	if p.roleId != "" {
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		body := map[string]string{"role_id": p.roleId, "secret_id": p.secretId}
+		if _, err := p.send(ctx, http.MethodPost, "/v1/auth/"+p.approle+"/login", nil, body, "", &resp); err != nil {
+			return "", fmt.Errorf("vault AppRole login: %v", err)
+		}
+		p.token = resp.Auth.ClientToken
+		return p.token, nil
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if token, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			p.token = strings.TrimSpace(string(token))
+			return p.token, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s or %s must be set to resolve Vault references", vaultTokenEnvVar, vaultRoleIdEnvVar)
+}
+
+func (p *vaultProvider) request(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) (found bool, err error) {
+	token, err := p.authToken(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.send(ctx, method, path, query, body, token, out)
+}
+
+func (p *vaultProvider) send(ctx context.Context, method string, path string, query url.Values, body interface{}, token string, out interface{}) (found bool, err error) {
+	endpoint := p.addr + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return false, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	p.log.Debug("REQ: Vault %s %s", method, path)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		err := fmt.Errorf("vault %s %s: %s %s", method, path, resp.Status, strings.Join(errResp.Errors, ", "))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return false, &retryableError{err}
+		}
+		return false, err
+	}
+
+	return true, json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testVaultToken = "s.test-token"
+
+// fakeVault serves 'secret/' KV v2 mount and 'kv/' KV v1 mount, AppRole login returns testVaultToken
+func fakeVault(t *testing.T) *httptest.Server {
+	kv2 := map[string]map[string]map[string]interface{}{
+		"service/api": {
+			"1": {"secret": "v1-secret"},
+			"2": {"secret": "v2-secret", "port": 8080},
+		},
+	}
+	kv1 := map[string]map[string]interface{}{
+		"service/api": {"secret": "kv1-secret"},
+	}
+
+	write := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Method != http.MethodPost || body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			write(w, map[string][]string{"errors": {"invalid role or secret ID"}})
+			return
+		}
+		write(w, map[string]interface{}{"auth": map[string]string{"client_token": testVaultToken}})
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			write(w, map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/secret/"):
+			write(w, map[string]interface{}{"data": map[string]interface{}{"path": "secret/", "options": map[string]string{"version": "2"}}})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv/"):
+			write(w, map[string]interface{}{"data": map[string]interface{}{"path": "kv/", "options": nil}})
+		case strings.HasPrefix(path, "secret/data/"):
+			versions, ok := kv2[strings.TrimPrefix(path, "secret/data/")]
+			version := r.URL.Query().Get("version")
+			if version == "" {
+				version = "2"
+			}
+			if !ok || versions[version] == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			write(w, map[string]interface{}{"data": map[string]interface{}{"data": versions[version]}})
+		case strings.HasPrefix(path, "kv/"):
+			data, ok := kv1[strings.TrimPrefix(path, "kv/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			write(w, map[string]interface{}{"data": data})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testVaultProvider(server *httptest.Server) *vaultProvider {
+	return &vaultProvider{
+		log:        &Log{ioWriter: io.Discard},
+		addr:       server.URL,
+		token:      testVaultToken,
+		approle:    defaultVaultApproleMount,
+		httpClient: server.Client(),
+		mounts:     make(map[string]vaultMount),
+	}
+}
+
+func TestParseVaultReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    vaultReference
+		wantErr bool
+	}{
+		{ref: "secret/service/api#secret", want: vaultReference{path: "secret/service/api", key: "secret"}},
+		{ref: "secret/service/api#secret?version=1", want: vaultReference{path: "secret/service/api", key: "secret", version: 1}},
+		{ref: "secret/service/api?version=1#secret", want: vaultReference{path: "secret/service/api", key: "secret", version: 1}},
+		{ref: "/secret/service/api/", want: vaultReference{path: "secret/service/api"}},
+		{ref: "secret/service/api?version=latest", wantErr: true},
+		{ref: "secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseVaultReference(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseVaultReference(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseVaultReference(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestVaultProviderResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		refs []string
+		want map[string]string
+	}{
+		{
+			name: "KV v2",
+			refs: []string{"secret/service/api#secret", "secret/service/api#port"},
+			want: map[string]string{"secret/service/api#secret": "v2-secret", "secret/service/api#port": "8080"},
+		},
+		{
+			name: "KV v2 version",
+			refs: []string{"secret/service/api#secret?version=1"},
+			want: map[string]string{"secret/service/api#secret?version=1": "v1-secret"},
+		},
+		{
+			name: "KV v2 whole secret",
+			refs: []string{"secret/service/api?version=1"},
+			want: map[string]string{"secret/service/api?version=1": `{"secret":"v1-secret"}`},
+		},
+		{
+			name: "KV v1",
+			refs: []string{"kv/service/api#secret"},
+			want: map[string]string{"kv/service/api#secret": "kv1-secret"},
+		},
+		{
+			name: "missing secret, version and field",
+			refs: []string{"secret/service/missing#secret", "secret/service/api#secret?version=7", "secret/service/api#missing", "kv/service/missing#secret"},
+			want: map[string]string{},
+		},
+	}
+
+	server := fakeVault(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testVaultProvider(server).Resolve(context.Background(), tt.refs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProviderAppRole(t *testing.T) {
+	p := testVaultProvider(fakeVault(t))
+	p.token = ""
+	p.roleId = "role"
+	p.secretId = "secret"
+
+	got, err := p.Resolve(context.Background(), []string{"secret/service/api#secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["secret/service/api#secret"] != "v2-secret" {
+		t.Errorf("Resolve() = %v", got)
+	}
+	if p.token != testVaultToken {
+		t.Errorf("token = %q, want AppRole client token", p.token)
+	}
+
+	p = testVaultProvider(fakeVault(t))
+	p.token = ""
+	p.roleId = "role"
+	p.secretId = "wrong"
+	if _, err := p.Resolve(context.Background(), []string{"secret/service/api#secret"}); err == nil || !strings.Contains(err.Error(), "AppRole login") {
+		t.Errorf("Resolve() error = %v, want AppRole login error", err)
+	}
+}
+
+func TestVaultProviderErrors(t *testing.T) {
+	server := fakeVault(t)
+	tests := []struct {
+		name string
+		p    func() *vaultProvider
+		ref  string
+	}{
+		{"address is not set", func() *vaultProvider { p := testVaultProvider(server); p.addr = ""; return p }, "secret/service/api#secret"},
+		{"permission denied", func() *vaultProvider { p := testVaultProvider(server); p.token = "wrong"; return p }, "secret/service/api#secret"},
+		{"mount not found", func() *vaultProvider { return testVaultProvider(server) }, "unknown/service/api#secret"},
+		{"KV v1 version", func() *vaultProvider { return testVaultProvider(server) }, "kv/service/api#secret?version=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.p().Resolve(context.Background(), []string{tt.ref}); err == nil {
+				t.Errorf("Resolve(%q) error is expected", tt.ref)
+			}
+		})
+	}
+}
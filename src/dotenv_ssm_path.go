@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"strings"
+)
+
+const (
+	// ssmPathPrefix marks a value as Parameter Store hierarchy, each parameter under the path becomes an env var
+	ssmPathPrefix = "ssm-path://"
+
+	// defaultPathBatchSize is the maximum number of parameters returned by GetParametersByPath at once
+	defaultPathBatchSize = 10
+)
+
+// Characters which are not allowed in env var names
+var envNameReplacer = strings.NewReplacer("/", "_", "-", "_", ".", "_")
+
+// loadParameterPaths replaces 'ssm-path://' entries and --path-prefix flags by parameters stored under those paths.
+// Paths are loaded in declaration order: lines of merged layers, then flags, so a later path overrides vars of earlier ones.
+// Vars defined in dotEnv file explicitly have priority over loaded ones. Loaded values are kept apart from the source,
+// so a value like 'cmd://...' written to Parameter Store is never resolved
+func (c *DotEnvCommand) loadParameterPaths(ctx context.Context) {
+	paths := append(c.takeParameterPaths(), c.pathPrefixes...)
+
+	loaded := make(map[string]string)
+	for _, path := range paths {
+		c.log.Debug("Loading parameters by path: %s", path)
+		for name, value := range c.getParametersByPath(ctx, path) {
+			envName := c.parameterEnvName(path, name)
+			if _, ok := loaded[envName]; ok {
+				c.log.Warning("Parameter: %s overrides already loaded environment variable: %s", name, envName)
+			}
+			loaded[envName] = value
+		}
+	}
+
+	c.pathValues = make(map[string]string)
+	for k, v := range loaded {
+		if _, ok := c.dotEnvMap[k]; !ok {
+			c.pathValues[k] = v
+		}
+	}
+}
+
+// takeParameterPaths takes 'ssm-path://' lines out of the source, so those aren't written to dotEnv file.
+// Several lines can share the name like '_', comments right above those lines are taken out as well.
+// Single-quoted values are literal, those are kept as is
+func (c *DotEnvCommand) takeParameterPaths() []string {
+	var paths []string
+	var kept, comments []dotEnvLine
+
+	for _, l := range c.dotEnvLines {
+		switch {
+		case !l.isVar() && strings.HasPrefix(strings.TrimSpace(l.raw), "#"):
+			comments = append(comments, l)
+			continue
+		case l.isVar() && l.quote != '\'' && strings.HasPrefix(l.value, ssmPathPrefix):
+			paths = append(paths, strings.TrimPrefix(l.value, ssmPathPrefix))
+			// the var can be defined by a later line or a layer, its value is kept then
+			if c.dotEnvMap[l.key] == l.value {
+				delete(c.dotEnvMap, l.key)
+			}
+			comments = nil
+			continue
+		}
+		kept = append(append(kept, comments...), l)
+		comments = nil
+	}
+	c.dotEnvLines = append(kept, comments...)
+
+	return paths
+}
+
+// rejectParameterPaths fails commands which dont load 'ssm-path://' lines, like dotenv-diff and dotenv-push
+func (c *DotEnvCommand) rejectParameterPaths(command string, refs map[string]string) error {
+	for _, k := range sortedKeys(refs) {
+		if !c.isLiteral(k) && strings.HasPrefix(refs[k], ssmPathPrefix) {
+			return fmt.Errorf("'%s' lines are not supported by %s, environment variable: %s refers to '%s'", ssmPathPrefix, command, k, refs[k])
+		}
+	}
+	return nil
+}
+
+func (c *DotEnvCommand) getParametersByPath(ctx context.Context, path string) map[string]string {
+	values := make(map[string]string)
+
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(c.decrypt),
+		MaxResults:     aws.Int64(defaultPathBatchSize),
+	}
+
+	for {
+		var resp *ssm.GetParametersByPathOutput
+		err := c.withRetry(ctx, "Path: "+path, func() (err error) {
+			resp, err = c.ssm.GetParametersByPathWithContext(ctx, input)
+			return err
+		})
+		c.log.must(err)
+
+		for _, p := range resp.Parameters {
+			if aws.StringValue(p.Type) != ssm.ParameterTypeString {
+				c.log.Mask(*p.Value)
+			}
+			values[*p.Name] = *p.Value
+		}
+
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+
+	if len(values) == 0 {
+		c.log.Warning("There are no parameters by path: %s in AWS Parameter Store", path)
+	}
+
+	return values
+}
+
+// parameterEnvName converts parameter name like '/production/service/db/host' to env var name like 'DB_HOST'
+func (c *DotEnvCommand) parameterEnvName(path string, name string) string {
+	if c.pathStripPrefix {
+		name = strings.TrimPrefix(name, strings.TrimSuffix(path, "/"))
+	}
+
+	name = envNameReplacer.Replace(strings.Trim(name, "/"))
+
+	if c.pathUpperCase {
+		name = strings.ToUpper(name)
+	}
+
+	return name
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTakeParameterPaths(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), strings.Join([]string{
+		"# database",
+		"DB_NAME=db",
+		"",
+		"# shared parameters",
+		"_=ssm-path:///production/shared/",
+		"# service parameters",
+		"_=ssm-path:///production/service/",
+		"",
+		"PORT=80 # http",
+		"# tail",
+	}, "\n"))
+
+	paths := c.takeParameterPaths()
+	if want := []string{"/production/shared/", "/production/service/"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+
+	if _, ok := c.dotEnvMap["_"]; ok {
+		t.Errorf("path var is left in vars: %v", c.dotEnvMap)
+	}
+
+	var raw []string
+	for _, l := range c.dotEnvLines {
+		raw = append(raw, l.raw)
+	}
+	if want := []string{"# database", "DB_NAME=db", "", "", "PORT=80 # http", "# tail"}; !reflect.DeepEqual(raw, want) {
+		t.Errorf("lines = %q, want %q", raw, want)
+	}
+}
+
+func TestTakeParameterPathsKeepsOtherValues(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), strings.Join([]string{
+		"_=ssm-path:///production/shared/",
+		"LITERAL='ssm-path:///production/literal/'",
+		"QUOTED=\"ssm-path:///production/quoted/\"",
+		"DOCS_URL=https://example.com/ssm-path://",
+		"OVERRIDDEN=ssm-path:///production/overridden/",
+		"OVERRIDDEN=value",
+	}, "\n"))
+
+	paths := c.takeParameterPaths()
+	if want := []string{"/production/shared/", "/production/quoted/", "/production/overridden/"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+
+	want := map[string]string{
+		"LITERAL":    "ssm-path:///production/literal/",
+		"DOCS_URL":   "https://example.com/ssm-path://",
+		"OVERRIDDEN": "value",
+	}
+	if !reflect.DeepEqual(c.dotEnvMap, want) {
+		t.Errorf("dotEnvMap = %v, want %v", c.dotEnvMap, want)
+	}
+
+	var keys []string
+	for _, l := range c.dotEnvLines {
+		keys = append(keys, l.key)
+	}
+	if want := []string{"LITERAL", "DOCS_URL", "OVERRIDDEN"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("lines = %q, want %q", keys, want)
+	}
+}
+
+func TestLoadParameterPathsDeclarationOrder(t *testing.T) {
+	c := withSource(t, testDotEnvCommand(t), strings.Join([]string{
+		"_=ssm-path:///b",
+		"_=ssm-path:///a",
+		"EXPLICIT=file",
+	}, "\n"))
+	c.pathPrefixes = []string{"/c"}
+	c.pathStripPrefix = true
+	c.pathUpperCase = true
+	c.ssm = &fakeSsm{parameters: map[string]string{
+		"/b/shared":   "b",
+		"/b/b_only":   "b",
+		"/a/shared":   "a",
+		"/a/flagged":  "a",
+		"/a/explicit": "a",
+		"/c/flagged":  "c",
+	}}
+
+	c.loadParameterPaths(context.Background())
+
+	// later paths win, flags are declared after lines, vars of the file win over every path
+	want := map[string]string{"SHARED": "a", "B_ONLY": "b", "FLAGGED": "c"}
+	if !reflect.DeepEqual(c.pathValues, want) {
+		t.Errorf("pathValues = %v, want %v", c.pathValues, want)
+	}
+}
+
+func TestRejectParameterPaths(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{"DB_HOST=ssm:///app/db/host", false},
+		{"LITERAL='ssm-path:///app/shared/'", false},
+		{"_=ssm-path:///app/shared/\nDB_HOST=ssm:///app/db/host", true},
+	}
+
+	for _, tt := range tests {
+		c := withSource(t, testDotEnvCommand(t), tt.source)
+		if err := c.rejectParameterPaths("dotenv-diff", c.dotEnvMap); (err != nil) != tt.wantErr {
+			t.Errorf("rejectParameterPaths(%q) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParameterEnvName(t *testing.T) {
+	tests := []struct {
+		path        string
+		name        string
+		stripPrefix bool
+		upperCase   bool
+		want        string
+	}{
+		{"/production/service/", "/production/service/db/host", true, true, "DB_HOST"},
+		{"/production/service", "/production/service/db-name.v2", true, true, "DB_NAME_V2"},
+		{"/production/service/", "/production/service/db/host", false, true, "PRODUCTION_SERVICE_DB_HOST"},
+		{"/production/service/", "/production/service/db/host", true, false, "db_host"},
+	}
+
+	for _, tt := range tests {
+		c := testDotEnvCommand(t)
+		c.pathStripPrefix = tt.stripPrefix
+		c.pathUpperCase = tt.upperCase
+		if got := c.parameterEnvName(tt.path, tt.name); got != tt.want {
+			t.Errorf("parameterEnvName(%q, %q) = %q, want %q", tt.path, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathValuesAreNotResolved(t *testing.T) {
+	c := testDotEnvCommand(t)
+	pwned := filepath.Join(c.app.projectPath, "pwned")
+
+	c = withSource(t, c, "_=ssm-path:///app/shared/\nHOST=ssm:///app/host\nURL=http://${HOST}/${PATH_HOST}")
+	c.ssm = &fakeSsm{parameters: map[string]string{
+		"/app/host":             "db",
+		"/app/shared/cmd":       "cmd://touch " + pwned,
+		"/app/shared/file":      "file:///etc/hostname",
+		"/app/shared/ref":       "ssm:///app/host",
+		"/app/shared/interp":    "${HOST}",
+		"/app/shared/path_host": "path",
+	}}
+	c.pathStripPrefix = true
+	c.pathUpperCase = true
+	c.registerProviders()
+
+	c.loadParameterPaths(context.Background())
+	if err := c.processDotEnv(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+		t.Errorf("command of loaded value is executed, stat error = %v", err)
+	}
+
+	want := map[string]string{
+		"CMD":    "cmd://touch " + pwned,
+		"FILE":   "file:///etc/hostname",
+		"REF":    "ssm:///app/host",
+		"INTERP": "${HOST}",
+		"HOST":   "db",
+		"URL":    "http://db/path",
+	}
+	for k, v := range want {
+		if c.dotEnvMap[k] != v {
+			t.Errorf("%s = %q, want %q", k, c.dotEnvMap[k], v)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+type templateOptions struct {
+	text string
+	file string
+}
+
+// templateData is available in the template, Project contains values of terraform.env
+type templateData struct {
+	Name        string
+	Value       string
+	Environment string
+	Project     map[string]string
+}
+
+func (c *DotEnvCommand) configureTemplateFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("template", "Template that determines SSM parameter name of env var, like '{{ if eq .Value \"secure\" }}/{{ env }}/{{ service }}/{{ toLower .Name }}{{ end }}'").
+		PlaceHolder("TEMPLATE").
+		StringVar(&c.templateOptions.text)
+
+	cmd.Flag("template-file", "File that contains template of SSM parameter names").
+		PlaceHolder("PATH").
+		StringVar(&c.templateOptions.file)
+}
+
+var errTemplateFlags = errors.New("--template and --template-file cant be used together")
+
+// referenceTemplate parses user template, otherwise the default one
+func (c *DotEnvCommand) referenceTemplate() *template.Template {
+	t, err := c.loadTemplate()
+	if err == errTemplateFlags {
+		c.log.ErrorFWithUsage("%v", err)
+	}
+	c.log.must(err)
+	return t
+}
+
+func (c *DotEnvCommand) loadTemplate() (*template.Template, error) {
+	o := c.templateOptions
+	if o.text != "" && o.file != "" {
+		return nil, errTemplateFlags
+	}
+
+	text := o.text
+	if o.file != "" {
+		content, err := os.ReadFile(GetFullPath(c.app.projectPath, o.file))
+		if err != nil {
+			return nil, err
+		}
+		text = string(content)
+	}
+
+	if text == "" {
+		return c.newTemplate(defaultTemplate)
+	}
+
+	project, err := c.readProjectConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.project = project
+	c.log.Debug("Template: %s", text)
+
+	return c.newTemplate(text)
+}
+
+// readProjectConfig reads terraform.env of the project or its parent folder, it's optional
+func (c *DotEnvCommand) readProjectConfig() (map[string]string, error) {
+	for _, path := range []string{
+		GetFullPath(c.app.projectPath, defaultProjectConfig),
+		filepath.Join(c.app.projectPath, "..", defaultProjectConfig),
+	} {
+		if isExists, _ := ValidateFile(path); isExists {
+			c.log.Debug("Project config: %s", path)
+			project, err := godotenv.Read(path)
+			if err != nil {
+				return nil, fmt.Errorf("project config %s: %v", path, err)
+			}
+			return project, nil
+		}
+	}
+	return map[string]string{}, nil
+}
+
+// templateFuncs of the command, those are evaluated by environment and project config
+func (c *DotEnvCommand) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env": func() string {
+			return c.environment
+		},
+		"service": func() string {
+			return c.project["NAME"]
+		},
+		"project": func(key string) string {
+			return c.project[key]
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	const secureTemplate = `{{ if eq .Value "secure" }}/{{ env }}/{{ service }}/{{ project "TEAM" }}/{{ toLower .Name }}{{ end }}`
+
+	tests := []struct {
+		name    string
+		options templateOptions
+		files   map[string]string
+		vars    map[string]string
+		want    map[string]string
+		err     string
+	}{
+		{
+			name: "default template",
+			vars: map[string]string{"DB_HOST": "ssm:///p/db/host", "DB_NAME": "app"},
+			want: map[string]string{"DB_HOST": "/p/db/host", "DB_NAME": ""},
+		},
+		{
+			name:    "template flag with env, service and project functions",
+			options: templateOptions{text: secureTemplate},
+			files:   map[string]string{defaultProjectConfig: "NAME=billing\nTEAM=payments\n"},
+			vars:    map[string]string{"DB_PASSWORD": "secure", "DB_NAME": "app"},
+			want:    map[string]string{"DB_PASSWORD": "/dev/billing/payments/db_password", "DB_NAME": ""},
+		},
+		{
+			name:    "template file",
+			options: templateOptions{file: "ssm.tmpl"},
+			files: map[string]string{
+				"ssm.tmpl":           secureTemplate,
+				defaultProjectConfig: "NAME=billing\nTEAM=payments\n",
+			},
+			vars: map[string]string{"DB_PASSWORD": "secure"},
+			want: map[string]string{"DB_PASSWORD": "/dev/billing/payments/db_password"},
+		},
+		{
+			name:    "project config of the parent folder",
+			options: templateOptions{text: secureTemplate},
+			files:   map[string]string{filepath.Join("..", defaultProjectConfig): "NAME=shared\nTEAM=core\n"},
+			vars:    map[string]string{"DB_PASSWORD": "secure"},
+			want:    map[string]string{"DB_PASSWORD": "/dev/shared/core/db_password"},
+		},
+		{
+			name:    "missing project config is optional",
+			options: templateOptions{text: secureTemplate},
+			vars:    map[string]string{"DB_PASSWORD": "secure"},
+			want:    map[string]string{"DB_PASSWORD": "/dev///db_password"},
+		},
+		{
+			name:    "template and template file are mutually exclusive",
+			options: templateOptions{text: secureTemplate, file: "ssm.tmpl"},
+			files:   map[string]string{"ssm.tmpl": secureTemplate},
+			err:     errTemplateFlags.Error(),
+		},
+		{
+			name:    "missing template file",
+			options: templateOptions{file: "missing.tmpl"},
+			err:     "missing.tmpl",
+		},
+		{
+			name:    "malformed project config",
+			options: templateOptions{text: secureTemplate},
+			files:   map[string]string{defaultProjectConfig: "NAME=\"billing\n"},
+			err:     "project config",
+		},
+		{
+			name:    "malformed template",
+			options: templateOptions{text: `{{ if .Value }}`},
+			err:     "unexpected EOF",
+		},
+		{
+			name:    "unknown template function",
+			options: templateOptions{text: `{{ region }}`},
+			err:     `function "region" not defined`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testDotEnvCommand(t)
+			// parent folder is a temp dir of the test as well
+			c.app.projectPath = filepath.Join(c.app.projectPath, "project")
+			if err := os.Mkdir(c.app.projectPath, 0700); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(c.app.projectPath, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c.templateOptions = tt.options
+
+			template, err := c.loadTemplate()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("loadTemplate() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.template = template
+			for k, v := range tt.vars {
+				parameter, err := c.parameter(k, v)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := ""
+				if parameter != nil {
+					got = *parameter
+				}
+				if got != tt.want[k] {
+					t.Errorf("parameter(%s) = %q, want %q", k, got, tt.want[k])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"gopkg.in/alecthomas/kingpin.v2"
+	"os"
+	"path/filepath"
+)
+
+// GenerateOptions controls how generated configuration will be applied to existing file
+type GenerateOptions struct {
+	dryRun bool
+	diff   bool
+	check  bool
+}
+
+func ConfigureGenerateFlags(cmd *kingpin.CmdClause, o *GenerateOptions) {
+	cmd.Flag("dry-run", "Print unified diff between existing and generated file, nothing will be written").
+		Default("false").
+		BoolVar(&o.dryRun)
+
+	cmd.Flag("diff", "Print unified diff between existing and generated file before it will be written").
+		Default("false").
+		BoolVar(&o.diff)
+
+	cmd.Flag("check", "Exit with non-zero code if existing file differs from generated one, nothing will be written").
+		Default("false").
+		BoolVar(&o.check)
+}
+
+// PreviewGenerated shows the difference between filePath and content according to options
+// proceed: true, generated content should be written
+func (a *App) PreviewGenerated(filePath string, content string, o *GenerateOptions) (proceed bool) {
+	if !o.dryRun && !o.diff && !o.check {
+		return true
+	}
+
+	name := filepath.Base(filePath)
+	fromName := "a/" + name
+
+	current, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		fromName = "/dev/null"
+	} else if err != nil {
+		a.isError(err)
+	}
+
+	diff := UnifiedDiff(fromName, "b/"+name, string(current), content)
+	if diff == "" {
+		a.log.Info("'%s' is up to date", name)
+		return false
+	}
+
+	a.log.Printf("%s", diff)
+
+	if o.check {
+		a.log.ErrorF("'%s' is out of date", name)
+	}
+
+	return !o.dryRun
+}
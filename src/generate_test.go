@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// previewGenerated runs PreviewGenerated against file of the temp dir, diff printed to stdout is returned
+func previewGenerated(t *testing.T, current *string, content string, o GenerateOptions) (proceed bool, stdout string, path string) {
+	t.Helper()
+
+	path = filepath.Join(t.TempDir(), "backend.tf")
+	if current != nil {
+		if err := os.WriteFile(path, []byte(*current), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	out := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = out }()
+
+	log := &Log{ioWriter: io.Discard}
+	proceed = (&App{log: log}).PreviewGenerated(path, content, &o)
+
+	w.Close()
+	printed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proceed, string(printed), path
+}
+
+func TestPreviewGenerated(t *testing.T) {
+	existing := "bucket = \"old\"\n"
+	generated := "bucket = \"new\"\n"
+	drift := "--- a/backend.tf\n+++ b/backend.tf\n@@ -1 +1 @@\n-bucket = \"old\"\n+bucket = \"new\"\n"
+
+	tests := []struct {
+		name    string
+		current *string
+		content string
+		options GenerateOptions
+		proceed bool
+		stdout  string
+	}{
+		{"no options", &existing, generated, GenerateOptions{}, true, ""},
+		{"dry run of drift", &existing, generated, GenerateOptions{dryRun: true}, false, drift},
+		{"dry run without drift", &existing, existing, GenerateOptions{dryRun: true}, false, ""},
+		{"dry run of new file", nil, generated, GenerateOptions{dryRun: true}, false, "--- /dev/null\n+++ b/backend.tf\n@@ -0,0 +1 @@\n+bucket = \"new\"\n"},
+		{"diff of drift", &existing, generated, GenerateOptions{diff: true}, true, drift},
+		{"diff without drift", &existing, existing, GenerateOptions{diff: true}, false, ""},
+		{"check without drift", &existing, existing, GenerateOptions{check: true}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proceed, stdout, path := previewGenerated(t, tt.current, tt.content, tt.options)
+
+			if proceed != tt.proceed {
+				t.Errorf("PreviewGenerated() = %v, want %v", proceed, tt.proceed)
+			}
+			if stdout != tt.stdout {
+				t.Errorf("PreviewGenerated() printed %q, want %q", stdout, tt.stdout)
+			}
+
+			// the file is written by the command only if it proceeds
+			content, err := os.ReadFile(path)
+			switch {
+			case tt.current == nil && !os.IsNotExist(err):
+				t.Errorf("new file is written, stat error = %v", err)
+			case tt.current != nil && string(content) != *tt.current:
+				t.Errorf("file content = %q, want %q", content, *tt.current)
+			}
+		})
+	}
+}
+
+// TestPreviewGeneratedCheck runs check in a child test process, since drift exits with non-zero code
+func TestPreviewGeneratedCheck(t *testing.T) {
+	if os.Getenv("TFCONFIG_TEST_CHECK") != "" {
+		path := filepath.Join(t.TempDir(), "backend.tf")
+		if err := os.WriteFile(path, []byte("bucket = \"old\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		log := &Log{ioWriter: os.Stderr}
+		(&App{log: log}).PreviewGenerated(path, "bucket = \"new\"\n", &GenerateOptions{check: true})
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPreviewGeneratedCheck$")
+	cmd.Env = append(os.Environ(), "TFCONFIG_TEST_CHECK=1")
+	out, err := cmd.CombinedOutput()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("check of drift error = %v, want exit code 1, output:\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "-bucket = \"old\"\n+bucket = \"new\"\n") {
+		t.Errorf("diff is not printed before exit:\n%s", out)
+	}
+	if !strings.Contains(string(out), "'backend.tf' is out of date") {
+		t.Errorf("drift is not reported:\n%s", out)
+	}
+}
@@ -5,9 +5,29 @@ import (
 	"gopkg.in/alecthomas/kingpin.v2"
 	"io"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
+const (
+	// secretMask replaces registered secrets in log lines
+	secretMask = "*****"
+
+	// minMaskedLength protects log lines from masking of short values like '1' or 'true'
+	minMaskedLength = 4
+)
+
+// AWS SDK logs request and response bodies before values are registered, so value fields are masked by names:
+// JSON bodies of Parameter Store, Secrets Manager and SSO, XML bodies of STS credentials and MFA token code of AssumeRole request
+var awsSecretFields = regexp.MustCompile(`"(Value|SecretString|SecretBinary|SecretAccessKey|SessionToken|secretAccessKey|sessionToken|accessToken)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+var awsSecretXmlFields = regexp.MustCompile(`<(SecretAccessKey|SessionToken)>[^<]*</(?:SecretAccessKey|SessionToken)>`)
+var awsSecretFormFields = regexp.MustCompile(`\b(TokenCode)=[^&\s]*`)
+
+// githubCommands receives GitHub Actions workflow commands, the runner reads those from stderr too
+var githubCommands io.Writer = os.Stderr
+
 type Log struct {
 	cli      *kingpin.Application
 	args     []string
@@ -16,6 +36,10 @@ type Log struct {
 	silent   bool
 	isQuite  bool
 	awsDebug bool
+	mu       sync.Mutex
+	secrets  []string
+	// masked contains whole secret values, short ones as well
+	masked map[string]bool
 }
 
 func (a *App) Logger() *Log {
@@ -49,31 +73,98 @@ func (l *Log) Quite() {
 }
 
 func (l *Log) ErrorF(format string, s ...interface{}) {
-	showLog("ERROR", fmt.Sprintf(format, s...), true, false, l.ioWriter)
+	showLog("ERROR", l.redact(fmt.Sprintf(format, s...)), true, false, l.ioWriter)
 }
 
 func (l *Log) Info(format string, s ...interface{}) {
-	showLog("INFO", fmt.Sprintf(format, s...), false, l.isQuite, l.ioWriter)
+	showLog("INFO", l.redact(fmt.Sprintf(format, s...)), false, l.isQuite, l.ioWriter)
 }
 
 func (l *Log) Debug(format string, s ...interface{}) {
 	if l.verbose {
-		showLog("DEBUG", fmt.Sprintf(format, s...), false, l.isQuite, l.ioWriter)
+		showLog("DEBUG", l.redact(fmt.Sprintf(format, s...)), false, l.isQuite, l.ioWriter)
 	}
 }
 
 func (l *Log) Warning(format string, s ...interface{}) {
-	showLog("WARNING", fmt.Sprintf(format, s...), false, l.isQuite, l.ioWriter)
+	showLog("WARNING", l.redact(fmt.Sprintf(format, s...)), false, l.isQuite, l.ioWriter)
 }
 
 func (l *Log) ShowOpts(name string, value string) {
-	showLog("INFO", fmt.Sprintf("%s:\t%s", name, value), false, l.isQuite, l.ioWriter)
+	showLog("INFO", l.redact(fmt.Sprintf("%s:\t%s", name, value)), false, l.isQuite, l.ioWriter)
 }
 
 func (l *Log) Printf(format string, s ...interface{}) {
 	fmt.Fprintf(os.Stdout, format, s...)
 }
 
+// Mask registers secret value which is redacted from every log line, lines of multiline values are masked separately.
+// GitHub Actions masks it in the job log as well
+func (l *Log) Mask(value string) {
+	parts := append([]string{value}, strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.masked == nil {
+		l.masked = make(map[string]bool)
+	}
+	l.masked[value] = true
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) < minMaskedLength || containsString(l.secrets, part) {
+			continue
+		}
+
+		l.secrets = append(l.secrets, part)
+		if isGithubActions() && !strings.ContainsAny(part, "\r\n") {
+			// stdout is kept for printed vars
+			fmt.Fprintf(githubCommands, "::add-mask::%s\n", strings.ReplaceAll(part, "%", "%25"))
+		}
+	}
+
+	// longer secrets are replaced first, so those are not partially revealed by shorter ones
+	sort.Slice(l.secrets, func(i, j int) bool {
+		return len(l.secrets[i]) > len(l.secrets[j])
+	})
+}
+
+// IsSecret reports whether the value has been registered by Mask or contains a registered secret,
+// like interpolated one 'pg://user:${DB_PASSWORD}@host'
+func (l *Log) IsSecret(value string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.masked[value] {
+		return true
+	}
+	for _, secret := range l.secrets {
+		if strings.Contains(value, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Log) redact(message string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, secret := range l.secrets {
+		message = strings.ReplaceAll(message, secret, secretMask)
+	}
+	return message
+}
+
+// AwsLog is AWS SDK logger, bodies are redacted as well as log lines
+func (l *Log) AwsLog(args ...interface{}) {
+	message := awsSecretFields.ReplaceAllString(fmt.Sprint(args...), `"$1":"`+secretMask+`"`)
+	message = awsSecretXmlFields.ReplaceAllString(message, `<$1>`+secretMask+`</$1>`)
+	message = awsSecretFormFields.ReplaceAllString(message, `$1=`+secretMask)
+	fmt.Fprintln(l.ioWriter, l.redact(message))
+}
+
 func (l *Log) must(err error) {
 	if err != nil {
 		l.ErrorF("Message: %v", err)
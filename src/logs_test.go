@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLogMask(t *testing.T) {
+	out := new(bytes.Buffer)
+	l := &Log{ioWriter: out}
+
+	l.Mask("PaSsW0rd")
+	l.Mask("PaSsW0rd-long")
+	l.Mask("abc")
+	l.Mask("-----BEGIN KEY-----\r\nline one\nline two\n-----END KEY-----")
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"password: PaSsW0rd", "password: *****"},
+		// longer secrets are replaced first, so the suffix is not revealed
+		{"token: PaSsW0rd-long", "token: *****"},
+		{"short: abc", "short: abc"},
+		{"multiline: line one, line two", "multiline: *****, *****"},
+		{"url: pg://user:PaSsW0rd@host", "url: pg://user:*****@host"},
+	}
+	for _, tt := range tests {
+		if got := l.redact(tt.message); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+
+	l.Warning("Value: %s", "PaSsW0rd")
+	if got := out.String(); got != "[WARNING]  Value: *****\n" {
+		t.Errorf("Warning() = %q", got)
+	}
+}
+
+func TestLogIsSecret(t *testing.T) {
+	l := &Log{ioWriter: new(bytes.Buffer)}
+	l.Mask("PaSsW0rd")
+	l.Mask("abc")
+
+	tests := map[string]bool{
+		"PaSsW0rd":                   true,
+		"abc":                        true,
+		"pg://user:PaSsW0rd@host/db": true,
+		"pg://user:abc@host/db":      false,
+		"plain":                      false,
+	}
+	for value, want := range tests {
+		if got := l.IsSecret(value); got != want {
+			t.Errorf("IsSecret(%q) = %t, want %t", value, got, want)
+		}
+	}
+}
+
+func TestLogMaskGithubActions(t *testing.T) {
+	commands := new(bytes.Buffer)
+	defer func(w io.Writer) { githubCommands = w }(githubCommands)
+	githubCommands = commands
+
+	t.Setenv(GithubActionsEnvVar, "true")
+
+	l := &Log{ioWriter: new(bytes.Buffer)}
+	l.Mask("100%secret")
+	l.Mask("100%secret")
+	l.Mask("abc")
+	l.Mask("first line\nsecond line")
+
+	want := "::add-mask::100%25secret\n::add-mask::first line\n::add-mask::second line\n"
+	if got := commands.String(); got != want {
+		t.Errorf("workflow commands = %q, want %q", got, want)
+	}
+}
+
+func TestAwsLog(t *testing.T) {
+	out := new(bytes.Buffer)
+	l := &Log{ioWriter: out}
+	l.Mask("registered-secret")
+
+	l.AwsLog(`{"Parameters":[{"Name":"/app/db","Value":"s3cr\"et","Version":1}]}`)
+	l.AwsLog(`{"SecretString":"{\"password\":\"p\"}","SecretBinary":"YmluYXJ5"}`)
+	l.AwsLog(`{"roleCredentials":{"accessKeyId":"ASIA","secretAccessKey":"sso-secret","sessionToken":"sso-token"}}`)
+	l.AwsLog("<Credentials>\n<AccessKeyId>ASIAEXAMPLE</AccessKeyId>\n<SecretAccessKey>wJalr/XUtn+FEMI</SecretAccessKey>\n" +
+		"<SessionToken>FwoGZXIvYXdz//token=</SessionToken>\n</Credentials>")
+	l.AwsLog("Action=AssumeRole&RoleArn=arn&SerialNumber=arn%3Amfa&TokenCode=123456&Version=2011-06-15")
+	l.AwsLog("DEBUG: Response body: registered-secret")
+
+	got := out.String()
+	for _, leaked := range []string{`s3cr`, "password", "YmluYXJ5", "sso-secret", "sso-token", "wJalr", "FwoGZXIvYXdz", "123456", "registered-secret"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("AWS log contains %q:\n%s", leaked, got)
+		}
+	}
+	for _, kept := range []string{`"Name":"/app/db"`, "<AccessKeyId>ASIAEXAMPLE</AccessKeyId>", "<SecretAccessKey>*****</SecretAccessKey>",
+		"<SessionToken>*****</SessionToken>", "TokenCode=*****&Version", `"accessKeyId":"ASIA"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("AWS log doesn't contain %q:\n%s", kept, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 const Version = "v0.5.1"
 
 const CiEnvVar = "CI"
+const GithubActionsEnvVar = "GITHUB_ACTIONS"
 const EnvVersionVar = "TF_ENV_VERSION"
 const TerraformLocalEnvVar = "TF_LOCAL"
 const TerraformEnvVar = "TF_ENV"
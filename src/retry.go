@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	// defaultRetryAttempts is the number of attempts for throttled and transient errors
+	defaultRetryAttempts = 5
+
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// retryableError marks provider errors that are worth retrying, like HTTP 429 or 5xx responses
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryableError detects throttling and transient errors, canceled or timed out operations are never retried
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+
+	// SDK treats unknown errors as retryable, so only AWS errors are checked by it
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return request.IsErrorThrottle(awsErr) || request.IsErrorRetryable(awsErr)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoffDelay is exponential backoff with full jitter
+func backoffDelay(attempt int) time.Duration {
+	delay := defaultRetryMaxDelay
+	if attempt < 16 {
+		if d := defaultRetryBaseDelay << uint(attempt); d < delay {
+			delay = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// withRetry runs the operation until it succeeds, fails with non-retryable error, attempts are over or ctx is done
+// AWS clients are created without SDK retries, so calls aren't retried twice
+func (c *DotEnvCommand) withRetry(ctx context.Context, name string, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= defaultRetryAttempts || !isRetryableError(err) {
+			return err
+		}
+
+		delay := backoffDelay(attempt)
+		c.log.Debug("%s: attempt %d failed: %v, retrying in %v", name, attempt, err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
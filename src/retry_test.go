@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"testing"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{awserr.New("ThrottlingException", "rate exceeded", nil), true},
+		{&retryableError{errors.New("vault: 503 Service Unavailable")}, true},
+		{fmt.Errorf("batch: %w", &retryableError{errors.New("429")}), true},
+		{awserr.New("AccessDeniedException", "denied", nil), false},
+		{context.Canceled, false},
+		{fmt.Errorf("batch: %w", context.DeadlineExceeded), false},
+		{errors.New("invalid reference"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryableError(tt.err); got != tt.want {
+			t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt < 40; attempt++ {
+		if d := backoffDelay(attempt); d < 0 || d > defaultRetryMaxDelay {
+			t.Errorf("backoffDelay(%d) = %v, want up to %v", attempt, d, defaultRetryMaxDelay)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	c := testDotEnvCommand(t)
+	throttled := awserr.New("ThrottlingException", "rate exceeded", nil)
+
+	attempts := 0
+	err := c.withRetry(context.Background(), "test", func() error {
+		if attempts++; attempts < 2 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("retryable error: err = %v, attempts = %d, want 2 attempts", err, attempts)
+	}
+
+	attempts = 0
+	denied := awserr.New("AccessDeniedException", "denied", nil)
+	err = c.withRetry(context.Background(), "test", func() error {
+		attempts++
+		return denied
+	})
+	if err != denied || attempts != 1 {
+		t.Errorf("non-retryable error: err = %v, attempts = %d, want 1 attempt", err, attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = c.withRetry(ctx, "test", func() error {
+		attempts++
+		return throttled
+	})
+	if !errors.Is(err, context.Canceled) || attempts != 1 {
+		t.Errorf("canceled context: err = %v, attempts = %d, want canceled after 1 attempt", err, attempts)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const defaultShell = "sh"
+
+// shellDialect describes how variables should be printed for a particular shell
+type shellDialect struct {
+	// export formats a statement that exports the variable, used with --export
+	export func(name string, value string) string
+	// plain formats the variable for plain output, separator included
+	plain func(name string, value string) string
+	// multiline: false, values with line breaks cant be represented
+	multiline bool
+	// plainUnsafe are characters which are mangled in plain output, values with those can be printed only with --export
+	plainUnsafe string
+}
+
+var shellDialects = map[string]shellDialect{
+	"sh": {
+		export:    func(n, v string) string { return "export " + n + "=" + shQuote(v) + "\n" },
+		plain:     func(n, v string) string { return n + "=" + v + " " },
+		multiline: true,
+		// 'env $(tfconfig dotenv dev)' splits output into words by whitespace and expands wildcards
+		plainUnsafe: " \t\r\n*?[",
+	},
+	"fish": {
+		export:    func(n, v string) string { return "set -gx " + n + " " + fishQuote(v) + "\n" },
+		plain:     func(n, v string) string { return n + "=" + v + "\n" },
+		multiline: true,
+		// 'env (tfconfig dotenv dev)' splits output only by line breaks
+		plainUnsafe: "\r\n",
+	},
+	"powershell": {
+		export:    func(n, v string) string { return "$env:" + n + " = " + psQuote(v) + "\n" },
+		plain:     func(n, v string) string { return "$env:" + n + " = " + psQuote(v) + "\n" },
+		multiline: true,
+	},
+	"cmd": {
+		export:    func(n, v string) string { return "set " + n + "=" + cmdEscape(v) + "\n" },
+		plain:     func(n, v string) string { return "set " + n + "=" + cmdEscape(v) + "\n" },
+		multiline: false,
+	},
+}
+
+// validate checks that every value can be printed, plain output is not quoted,
+// since quotes would be passed as is by command substitution
+func (d shellDialect) validate(shell string, vars map[string]string, export bool) error {
+	for _, k := range sortedKeys(vars) {
+		if !d.multiline && strings.ContainsAny(vars[k], "\r\n") {
+			return fmt.Errorf("value of '%s' contains line breaks and cant be printed for '%s' shell", k, shell)
+		}
+		if !export && strings.ContainsAny(vars[k], d.plainUnsafe) {
+			return fmt.Errorf("value of '%s' contains whitespace or wildcards and cant be printed unquoted for '%s' shell, use --export", k, shell)
+		}
+	}
+	return nil
+}
+
+func shellNames() []string {
+	names := make([]string, 0, len(shellDialects))
+	for k := range shellDialects {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// POSIX sh: nothing is special inside single quotes, single quote itself is closed, escaped and reopened
+func shQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// fish: only backslash and single quote are special inside single quotes
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// PowerShell: single quotes (including typographic ones) are escaped by doubling
+func psQuote(value string) string {
+	return "'" + strings.NewReplacer(`'`, `''`, "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛").Replace(value) + "'"
+}
+
+// cmd: special characters are escaped by caret, percent sign is doubled as it's expected to be run as batch file
+func cmdEscape(value string) string {
+	return strings.NewReplacer(
+		`^`, `^^`,
+		`&`, `^&`,
+		`|`, `^|`,
+		`<`, `^<`,
+		`>`, `^>`,
+		`(`, `^(`,
+		`)`, `^)`,
+		`"`, `^"`,
+		`%`, `%%`,
+	).Replace(value)
+}
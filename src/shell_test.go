@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestShellQuoting(t *testing.T) {
+	tests := []struct {
+		value string
+		sh    string
+		fish  string
+		ps    string
+		cmd   string
+	}{
+		{"plain", `'plain'`, `'plain'`, `'plain'`, `plain`},
+		{"it's", `'it'\''s'`, `'it\'s'`, `'it''s'`, `it's`},
+		{"$HOME ${X}", `'$HOME ${X}'`, `'$HOME ${X}'`, `'$HOME ${X}'`, `$HOME ${X}`},
+		{"`id` $(id)", "'`id` $(id)'", "'`id` $(id)'", "'`id` $(id)'", "`id` $^(id^)"},
+		{`back\slash`, `'back\slash'`, `'back\\slash'`, `'back\slash'`, `back\slash`},
+		{"100% ^up", `'100% ^up'`, `'100% ^up'`, `'100% ^up'`, `100%% ^^up`},
+		{`a&b|c<d>e"f`, `'a&b|c<d>e"f'`, `'a&b|c<d>e"f'`, `'a&b|c<d>e"f'`, `a^&b^|c^<d^>e^"f`},
+		{"‘typographic’", "'‘typographic’'", "'‘typographic’'", "'‘‘typographic’’'", "‘typographic’"},
+		{"line 1\nline 2", "'line 1\nline 2'", "'line 1\nline 2'", "'line 1\nline 2'", "line 1\nline 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := shQuote(tt.value); got != tt.sh {
+				t.Errorf("shQuote() = %s, want %s", got, tt.sh)
+			}
+			if got := fishQuote(tt.value); got != tt.fish {
+				t.Errorf("fishQuote() = %s, want %s", got, tt.fish)
+			}
+			if got := psQuote(tt.value); got != tt.ps {
+				t.Errorf("psQuote() = %s, want %s", got, tt.ps)
+			}
+			if got := cmdEscape(tt.value); got != tt.cmd {
+				t.Errorf("cmdEscape() = %s, want %s", got, tt.cmd)
+			}
+		})
+	}
+}
+
+func TestShellDialectOutput(t *testing.T) {
+	tests := []struct {
+		shell  string
+		export string
+		plain  string
+	}{
+		{"sh", "export A='$x'\n", "A=$x "},
+		{"fish", "set -gx A '$x'\n", "A=$x\n"},
+		{"powershell", "$env:A = '$x'\n", "$env:A = '$x'\n"},
+		{"cmd", "set A=$x\n", "set A=$x\n"},
+	}
+
+	for _, tt := range tests {
+		d := shellDialects[tt.shell]
+		if got := d.export("A", "$x"); got != tt.export {
+			t.Errorf("%s export = %q, want %q", tt.shell, got, tt.export)
+		}
+		if got := d.plain("A", "$x"); got != tt.plain {
+			t.Errorf("%s plain = %q, want %q", tt.shell, got, tt.plain)
+		}
+	}
+}
+
+func TestShellDialectValidate(t *testing.T) {
+	tests := []struct {
+		shell   string
+		value   string
+		export  bool
+		isValid bool
+	}{
+		{"sh", "p@$$`w0rd'", false, true},
+		{"sh", "two words", false, false},
+		{"sh", "two words", true, true},
+		{"sh", "line 1\nline 2", false, false},
+		{"sh", "line 1\nline 2", true, true},
+		{"sh", "glob*", false, false},
+		{"sh", "glob*", true, true},
+		{"fish", "two words", false, true},
+		{"fish", "line 1\nline 2", false, false},
+		{"fish", "line 1\nline 2", true, true},
+		{"powershell", "line 1\nline 2", false, true},
+		{"cmd", "two words", false, true},
+		{"cmd", "line 1\nline 2", true, false},
+		{"cmd", "line 1\r\nline 2", false, false},
+	}
+
+	for _, tt := range tests {
+		err := shellDialects[tt.shell].validate(tt.shell, map[string]string{"A": tt.value}, tt.export)
+		if (err == nil) != tt.isValid {
+			t.Errorf("%s validate(%q, export: %t) error = %v, want valid: %t", tt.shell, tt.value, tt.export, err, tt.isValid)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/joho/godotenv"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -91,6 +92,24 @@ func (a *App) ReplaceFile(filePath string, content string) {
 	}
 }
 
+func (a *App) AppendFile(filePath string, content string) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if a.isError(err) {
+		return
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	if a.isError(err) {
+		return
+	}
+
+	err = file.Sync()
+	if a.isError(err) {
+		return
+	}
+}
+
 func (a *App) FindFolder(path string, dir string) (isFound bool) {
 	file, err := os.Open(path)
 	if a.isError(err) {
@@ -180,3 +199,25 @@ func (a *App) findModules(path string, modulesDir string) (modulesPath string, i
 func listSearchPaths() (paths []string) {
 	return []string{"./", "../", "../../", "../../../", "../../../../"}
 }
+
+func isGithubActions() bool {
+	return os.Getenv(GithubActionsEnvVar) == "true"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}